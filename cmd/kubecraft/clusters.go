@@ -0,0 +1,676 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"KubeCraft/internal/addon"
+	"KubeCraft/internal/backup"
+	"KubeCraft/internal/certs"
+	"KubeCraft/internal/cluster"
+	"KubeCraft/internal/deploy"
+	"KubeCraft/internal/etcd"
+	"KubeCraft/internal/initialize"
+	"KubeCraft/internal/kubeconfig"
+	"KubeCraft/internal/reset"
+	"KubeCraft/internal/scale"
+	"KubeCraft/internal/upgrade"
+	"KubeCraft/internal/utils"
+	"KubeCraft/internal/verify"
+)
+
+// runInitialization 在集群锁内执行初始化。config.json 与 inventory 为全局文件，只能在持有锁时写入；
+// 尚未部署的集群只分配 ID 用于加锁，不保存记录
+func runInitialization(config utils.Config, reporter *SSEProgressReporter) error {
+	record, err := clusterStore.FindOrCreate(config)
+	if err != nil {
+		return err
+	}
+	return withClusterLock(record, func() error {
+		return initialize.Process(config, reporter)
+	})
+}
+
+// runDeployment 执行部署，并在集群记录中保存部署状态与 admin kubeconfig
+func runDeployment(config utils.Config, reporter *SSEProgressReporter) (*cluster.Record, error) {
+	record, err := clusterStore.FindOrCreate(config)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := clusterStore.TryLock(record.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	record.Status = cluster.StatusDeploying
+	if err := clusterStore.Save(record); err != nil {
+		return nil, err
+	}
+	log.Printf("Deploying cluster %s", record.ID)
+
+	err = deploy.Process(config, clusterStore.EtcdPKIDir(record.ID), reporter)
+	if err == nil {
+		reporter.ReportProgress("保存集群 kubeconfig...")
+		err = deploy.FetchKubeconfig(clusterStore.KubeconfigPath(record.ID))
+	}
+	if err == nil {
+		err = runVerification(record, reporter)
+	}
+
+	record.Status = cluster.StatusReady
+	if err != nil {
+		record.Status = cluster.StatusFailed
+	}
+	if saveErr := clusterStore.Save(record); saveErr != nil {
+		log.Printf("Failed to save cluster %s: %v", record.ID, saveErr)
+	}
+
+	return record, err
+}
+
+// listClusters 返回所有集群记录
+func listClusters(w http.ResponseWriter, r *http.Request) {
+	records, err := clusterStore.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for _, record := range records {
+		redact(record)
+	}
+	writeJSON(w, records)
+}
+
+// getCluster 返回单个集群记录
+func getCluster(w http.ResponseWriter, r *http.Request) {
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	redact(record)
+	writeJSON(w, record)
+}
+
+// AddNodesRequest 添加节点请求
+type AddNodesRequest struct {
+	Nodes        map[string]string `json:"nodes"`        // 主机名 -> IP
+	SecondaryIPs map[string]string `json:"secondaryIPs"` // 双栈集群中新主机的第二地址
+}
+
+// addNodes 处理添加 worker 节点，通过 SSE 推送进度
+func addNodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req AddNodesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	reporter := newSSEProgressReporter(w, len(req.Nodes)+6)
+	err = withClusterLock(record, func() error {
+		return scale.AddNodes(clusterStore, record, req.Nodes, req.SecondaryIPs, reporter)
+	})
+	reporter.Finish(err, "节点添加完成", "节点添加失败")
+}
+
+// AddMastersRequest 添加控制面节点请求
+type AddMastersRequest struct {
+	Masters      map[string]string `json:"masters"`      // 主机名 -> IP
+	SecondaryIPs map[string]string `json:"secondaryIPs"` // 双栈集群中新主机的第二地址
+}
+
+// addMasters 处理添加控制面节点，通过 SSE 推送进度
+func addMasters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req AddMastersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	reporter := newSSEProgressReporter(w, len(req.Masters)+11)
+	err = withClusterLock(record, func() error {
+		return scale.AddMasters(clusterStore, record, req.Masters, req.SecondaryIPs, reporter)
+	})
+	reporter.Finish(err, "控制面节点添加完成", "控制面节点添加失败")
+}
+
+// removeMaster 处理移除控制面节点，通过 SSE 推送进度
+func removeMaster(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	reporter := newSSEProgressReporter(w, 9)
+	err = withClusterLock(record, func() error {
+		return scale.RemoveMaster(clusterStore, record, r.PathValue("hostname"), reporter)
+	})
+	reporter.Finish(err, "控制面节点移除完成", "控制面节点移除失败")
+}
+
+// removeNode 处理移除 worker 节点，驱逐选项通过查询参数指定，通过 SSE 推送进度
+func removeNode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	options, err := parseRemoveOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	reporter := newSSEProgressReporter(w, 8)
+	err = withClusterLock(record, func() error {
+		return scale.RemoveNode(clusterStore, record, r.PathValue("hostname"), options, reporter)
+	})
+	reporter.Finish(err, "节点移除完成", "节点移除失败")
+}
+
+// parseRemoveOptions 解析 gracePeriod、timeout、disableEviction、force 查询参数
+func parseRemoveOptions(r *http.Request) (scale.RemoveOptions, error) {
+	options := scale.RemoveOptions{GracePeriod: -1}
+	query := r.URL.Query()
+
+	var err error
+	if v := query.Get("gracePeriod"); v != "" {
+		if options.GracePeriod, err = strconv.Atoi(v); err != nil {
+			return options, fmt.Errorf("invalid gracePeriod: %v", err)
+		}
+	}
+	if v := query.Get("timeout"); v != "" {
+		if options.Timeout, err = strconv.Atoi(v); err != nil {
+			return options, fmt.Errorf("invalid timeout: %v", err)
+		}
+	}
+	if v := query.Get("disableEviction"); v != "" {
+		if options.DisableEviction, err = strconv.ParseBool(v); err != nil {
+			return options, fmt.Errorf("invalid disableEviction: %v", err)
+		}
+	}
+	if v := query.Get("force"); v != "" {
+		if options.Force, err = strconv.ParseBool(v); err != nil {
+			return options, fmt.Errorf("invalid force: %v", err)
+		}
+	}
+	return options, nil
+}
+
+// upgradeCluster 处理 Kubernetes 版本滚动升级，通过 SSE 推送进度
+func upgradeCluster(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req upgrade.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	addons, err := addon.Enabled(record.Config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	total := 4 + 4*len(record.Config.Masters) + 3*len(record.Config.Nodes) + len(addons)
+	reporter := newSSEProgressReporter(w, total)
+	err = withClusterLock(record, func() error {
+		return upgrade.Upgrade(clusterStore, record, req, reporter)
+	})
+	reporter.Finish(err, fmt.Sprintf("集群已升级到 %s", req.Version), "集群升级失败")
+}
+
+// resetCluster 处理重置集群或部分主机，通过 SSE 推送进度
+func resetCluster(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req reset.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	reporter := newSSEProgressReporter(w, reset.Steps(record.Config, req))
+	err = withClusterLock(record, func() error {
+		return reset.Reset(clusterStore, record, req, reporter)
+	})
+	reporter.Finish(err, "重置完成", "重置失败")
+}
+
+// clusterCerts 返回各 Master 的证书过期信息
+func clusterCerts(w http.ResponseWriter, r *http.Request) {
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	var hosts []certs.HostCertificates
+	err = withClusterLock(record, func() error {
+		hosts, err = certs.Check(clusterStore, record)
+		return err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, hosts)
+}
+
+// renewCerts 处理证书续期，通过 SSE 推送进度
+func renewCerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	reporter := newSSEProgressReporter(w, 3)
+	err = withClusterLock(record, func() error {
+		return certs.Renew(clusterStore, record, reporter)
+	})
+	reporter.Finish(err, "证书续期完成", "证书续期失败")
+}
+
+// clusterBackups 处理 etcd 快照：GET 返回快照列表，POST 立即生成快照
+func clusterBackups(w http.ResponseWriter, r *http.Request) {
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		snapshots, err := backup.List(clusterStore, record)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, snapshots)
+	case http.MethodPost:
+		var snapshot etcd.Snapshot
+		err = withClusterLock(record, func() error {
+			snapshot, err = backup.Snapshot(clusterStore, record)
+			return err
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, snapshot)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// backupPolicy 更新集群的定时备份策略
+func backupPolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var policy cluster.BackupPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if policy.IntervalHours < 0 || policy.Retention < 0 {
+		http.Error(w, "intervalHours and retention must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	err = withClusterLock(record, func() error {
+		record.Backup = policy
+		return clusterStore.Save(record)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, record.Backup)
+}
+
+// restoreBackup 处理从快照恢复 etcd，通过 SSE 推送进度
+func restoreBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	reporter := newSSEProgressReporter(w, 4)
+	err = withClusterLock(record, func() error {
+		return backup.Restore(clusterStore, record, r.PathValue("name"), reporter)
+	})
+	reporter.Finish(err, "etcd 快照恢复完成", "etcd 快照恢复失败")
+}
+
+// adminKubeconfig 返回集群 admin kubeconfig
+func adminKubeconfig(w http.ResponseWriter, r *http.Request) {
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	data, err := kubeconfig.Admin(clusterStore, record)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeKubeconfig(w, record.ID+"-admin.conf", data)
+}
+
+// issueUserKubeconfig 签发用户证书并返回用户 kubeconfig
+func issueUserKubeconfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req kubeconfig.UserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	data, err := kubeconfig.IssueUser(clusterStore, record, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeKubeconfig(w, fmt.Sprintf("%s-%s.conf", record.ID, req.Username), data)
+}
+
+// writeKubeconfig 以附件形式返回 kubeconfig
+func writeKubeconfig(w http.ResponseWriter, filename string, data []byte) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Cache-Control", "no-store")
+	if _, err := w.Write(data); err != nil {
+		log.Printf("Failed to write kubeconfig: %v", err)
+	}
+}
+
+// requireAPIToken 校验 Authorization: Bearer <token>，token 由环境变量 KUBECRAFT_API_TOKEN 指定，未设置时拒绝访问
+func requireAPIToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := os.Getenv("KUBECRAFT_API_TOKEN")
+		if token == "" {
+			http.Error(w, "credential API is disabled, set KUBECRAFT_API_TOKEN to enable it", http.StatusForbidden)
+			return
+		}
+
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// protectMutation 保护初始化、部署与修改集群的接口：不返回 CORS 头并拒绝跨站请求，避免任意网页借助浏览器调用；
+// 设置 KUBECRAFT_API_TOKEN 时 GET 以外的请求还需携带 token
+func protectMutation(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || u.Host != r.Host {
+				http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+				return
+			}
+		}
+
+		if r.Method == http.MethodGet || os.Getenv("KUBECRAFT_API_TOKEN") == "" {
+			next(w, r)
+			return
+		}
+		requireAPIToken(next)(w, r)
+	}
+}
+
+// runVerification 验证集群并保存报告，存在未通过的检查时返回错误
+func runVerification(record *cluster.Record, reporter verify.ProgressReporter) error {
+	report := verify.Run(clusterStore.Kubeconfig(record.ID), record.Config, reporter)
+	if err := report.Save(verifyReportPath(record.ID)); err != nil {
+		log.Printf("Failed to save verification report of cluster %s: %v", record.ID, err)
+	}
+
+	if !report.Passed {
+		return fmt.Errorf("verification failed: %s", strings.Join(report.Failed(), ", "))
+	}
+	return nil
+}
+
+// verifyReportPath 返回集群最近一次验证报告的保存路径
+func verifyReportPath(id string) string {
+	return filepath.Join(clusterStore.Dir(id), "verify.json")
+}
+
+// verifyCluster 处理集群验证：GET 返回最近一次报告，POST 重新验证并通过 SSE 推送进度
+func verifyCluster(w http.ResponseWriter, r *http.Request) {
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		data, err := os.ReadFile(verifyReportPath(record.ID))
+		if err != nil {
+			http.Error(w, "no verification report", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	case http.MethodPost:
+		reporter := newSSEProgressReporter(w, verify.Steps())
+		err = withClusterLock(record, func() error {
+			return runVerification(record, reporter)
+		})
+		reporter.Finish(err, "集群验证通过", "集群验证失败")
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// clusterAddons 返回集群所有附加组件的启用状态与运行状态
+func clusterAddons(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	config := record.Config
+	config.ApplyDefaults()
+	writeJSON(w, addon.Statuses(addon.Context{Config: config, Kubeconfig: clusterStore.Kubeconfig(record.ID)}))
+}
+
+// manageAddon 处理单个附加组件：POST 启用并安装，DELETE 卸载并禁用，通过 SSE 推送进度
+func manageAddon(w http.ResponseWriter, r *http.Request) {
+	var enabled bool
+	switch r.Method {
+	case http.MethodPost:
+		enabled = true
+	case http.MethodDelete:
+		enabled = false
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	record, err := clusterStore.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	name := r.PathValue("name")
+	if _, ok := addon.Get(name); !ok {
+		http.Error(w, fmt.Sprintf("unknown addon %q", name), http.StatusNotFound)
+		return
+	}
+
+	reporter := newSSEProgressReporter(w, 2)
+	err = withClusterLock(record, func() error {
+		return addon.SetEnabled(clusterStore, record, name, enabled, reporter)
+	})
+	if enabled {
+		reporter.Finish(err, fmt.Sprintf("%s 安装完成", name), fmt.Sprintf("%s 安装失败", name))
+	} else {
+		reporter.Finish(err, fmt.Sprintf("%s 卸载完成", name), fmt.Sprintf("%s 卸载失败", name))
+	}
+}
+
+// withClusterLock 在集群操作锁内执行 fn
+func withClusterLock(record *cluster.Record, fn func() error) error {
+	unlock, err := clusterStore.TryLock(record.ID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return fn()
+}
+
+// redact 隐藏集群记录中的 SSH 密码、VRRP 认证密码、BGP 密码与镜像仓库密码。
+// 切片先复制再修改，避免影响与记录共享底层数组的配置。
+func redact(record *cluster.Record) {
+	config := &record.Config
+	if config.SshPass != "" {
+		config.SshPass = "******"
+	}
+	if config.Keepalived.AuthPass != "" {
+		config.Keepalived.AuthPass = "******"
+	}
+	config.MetalLB.Peers = slices.Clone(config.MetalLB.Peers)
+	for i := range config.MetalLB.Peers {
+		if config.MetalLB.Peers[i].Password != "" {
+			config.MetalLB.Peers[i].Password = "******"
+		}
+	}
+	config.Mirrors.Registries = redactRegistries(config.Mirrors.Registries)
+	config.Resolved.Registries = redactRegistries(config.Resolved.Registries)
+}
+
+// redactRegistries 返回隐藏密码后的仓库配置副本
+func redactRegistries(registries []utils.RegistryMirror) []utils.RegistryMirror {
+	registries = slices.Clone(registries)
+	for i := range registries {
+		if registries[i].Password != "" {
+			registries[i].Password = "******"
+		}
+	}
+	return registries
+}
+
+// writeJSON 以 JSON 格式返回响应
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
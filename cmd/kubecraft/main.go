@@ -1,7 +1,11 @@
 package main
 
 import (
+	"KubeCraft/internal/artifact"
+	"KubeCraft/internal/backup"
+	"KubeCraft/internal/cluster"
 	"KubeCraft/internal/utils"
+	"KubeCraft/internal/verify"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -19,8 +23,8 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// 设置CORS头部
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		// 如果是OPTIONS预检请求，直接返回
 		if r.Method == "OPTIONS" {
@@ -61,10 +65,82 @@ func (s *SSEProgressReporter) ReportProgress(message string) {
 	time.Sleep(500 * time.Millisecond)
 }
 
+// artifactServer 内置制品服务器
+var artifactServer = artifact.NewServer(artifact.DefaultRoot)
+
+// clusterStore 集群记录存储
+var clusterStore = cluster.NewStore(cluster.DefaultRoot)
+
+// newSSEProgressReporter 设置 SSE 响应头并创建进度报告器
+func newSSEProgressReporter(w http.ResponseWriter, total int) *SSEProgressReporter {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	return &SSEProgressReporter{
+		writer: w,
+		step:   0,
+		total:  total,
+	}
+}
+
+// Finish 发送完成或错误消息
+func (s *SSEProgressReporter) Finish(err error, completeMessage, errorPrefix string) {
+	message := ProgressMessage{
+		Type:    "complete",
+		Message: completeMessage,
+		Step:    s.step,
+		Total:   s.total,
+	}
+	if err != nil {
+		log.Printf("%s: %v", errorPrefix, err)
+		message.Type = "error"
+		message.Message = fmt.Sprintf("%s: %v", errorPrefix, err)
+	}
+
+	data, _ := json.Marshal(message)
+	_, err = fmt.Fprintf(s.writer, "data: %s\n\n", string(data))
+	if err != nil {
+		return
+	}
+	s.writer.(http.Flusher).Flush()
+}
+
 func main() {
-	// 注册API路由处理器，添加CORS支持
-	http.HandleFunc("/api/init/progress", corsMiddleware(initializeProgress))
-	http.HandleFunc("/api/deploy/progress", corsMiddleware(deployProgress))
+	// 扫描制品目录并校验离线包
+	if err := artifactServer.Scan(); err != nil {
+		log.Printf("Artifact verification failed: %v", err)
+	}
+
+	// 启动 etcd 定时备份
+	backup.NewScheduler(clusterStore).Start(5 * time.Minute)
+
+	// 注册API路由处理器，添加CORS支持，初始化、部署与修改集群的接口只允许同源访问
+	http.HandleFunc("/api/init/progress", protectMutation(initializeProgress))
+	http.HandleFunc("/api/deploy/progress", protectMutation(deployProgress))
+	http.HandleFunc("/api/artifacts/status", corsMiddleware(artifactStatus))
+	http.HandleFunc("/api/artifacts/rescan", protectMutation(rescanArtifacts))
+	http.HandleFunc("/api/clusters", corsMiddleware(listClusters))
+	http.HandleFunc("/api/clusters/{id}", corsMiddleware(getCluster))
+	http.HandleFunc("/api/clusters/{id}/nodes", protectMutation(addNodes))
+	http.HandleFunc("/api/clusters/{id}/nodes/{hostname}", protectMutation(removeNode))
+	http.HandleFunc("/api/clusters/{id}/masters", protectMutation(addMasters))
+	http.HandleFunc("/api/clusters/{id}/masters/{hostname}", protectMutation(removeMaster))
+	http.HandleFunc("/api/clusters/{id}/upgrade", protectMutation(upgradeCluster))
+	http.HandleFunc("/api/clusters/{id}/reset", protectMutation(resetCluster))
+	http.HandleFunc("/api/clusters/{id}/certs", corsMiddleware(clusterCerts))
+	http.HandleFunc("/api/clusters/{id}/certs/renew", protectMutation(renewCerts))
+	http.HandleFunc("/api/clusters/{id}/backups", protectMutation(clusterBackups))
+	http.HandleFunc("/api/clusters/{id}/backups/policy", protectMutation(backupPolicy))
+	http.HandleFunc("/api/clusters/{id}/backups/{name}/restore", protectMutation(restoreBackup))
+	http.HandleFunc("/api/clusters/{id}/verify", protectMutation(verifyCluster))
+	http.HandleFunc("/api/clusters/{id}/addons", corsMiddleware(clusterAddons))
+	http.HandleFunc("/api/clusters/{id}/addons/{name}", protectMutation(manageAddon))
+	http.HandleFunc("/api/clusters/{id}/kubeconfig", corsMiddleware(requireAPIToken(adminKubeconfig)))
+	http.HandleFunc("/api/clusters/{id}/kubeconfig/users", corsMiddleware(requireAPIToken(issueUserKubeconfig)))
+
+	// 提供制品下载服务
+	http.Handle("/artifacts/", http.StripPrefix("/artifacts", artifactServer))
 
 	// 提供静态文件服务
 	http.HandleFunc("/", serveStaticFiles)
@@ -131,13 +207,9 @@ func initializeProgress(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Successfully decoded config: %+v", config)
 		log.Printf("Masters: %+v", config.Masters)
 		log.Printf("Nodes: %+v", config.Nodes)
-
-		// 将配置保存到项目根目录的 config.json 文件中
-		if err := saveConfigToFile(config, "config.json"); err != nil {
-			log.Printf("Failed to save config to file: %v", err)
-		} else {
-			log.Printf("Config successfully saved to config.json")
-		}
+		setDefaultArtifactsURL(&config)
+		inheritClusterSecrets(&config)
+		config.ApplyDefaults()
 	}
 
 	// 创建进度报告器
@@ -148,7 +220,7 @@ func initializeProgress(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 执行初始化过程
-	err := initialize.Process(config, reporter)
+	err := runInitialization(config, reporter)
 
 	// 发送完成或错误消息
 	if err != nil {
@@ -209,24 +281,20 @@ func deployProgress(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Successfully decoded config: %+v", config)
 		log.Printf("Masters: %+v", config.Masters)
 		log.Printf("Nodes: %+v", config.Nodes)
-
-		// 将配置保存到项目根目录的 config.json 文件中
-		if err := saveConfigToFile(config, "config.json"); err != nil {
-			log.Printf("Failed to save config to file: %v", err)
-		} else {
-			log.Printf("Config successfully saved to config.json")
-		}
+		setDefaultArtifactsURL(&config)
+		inheritClusterSecrets(&config)
+		config.ApplyDefaults()
 	}
 
 	// 创建进度报告器
 	reporter := &SSEProgressReporter{
 		writer: w,
 		step:   0,
-		total:  len(deploy.Playbooks) + 1, // 1个额外步骤：安装附加组件
+		total:  len(deploy.Playbooks(config)) + 1 + verify.Steps(), // 额外步骤：安装附加组件与部署验证
 	}
 
 	// 执行部署过程
-	err := deploy.Process(config, reporter)
+	record, err := runDeployment(config, reporter)
 
 	// 发送完成或错误消息
 	if err != nil {
@@ -245,7 +313,7 @@ func deployProgress(w http.ResponseWriter, r *http.Request) {
 	} else {
 		complete := ProgressMessage{
 			Type:    "complete",
-			Message: "集群部署完成",
+			Message: fmt.Sprintf("集群部署完成，集群 ID: %s", record.ID),
 			Step:    reporter.step,
 			Total:   reporter.total,
 		}
@@ -258,21 +326,45 @@ func deployProgress(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// saveConfigToFile 将配置保存到文件
-func saveConfigToFile(config utils.Config, filename string) error {
-	// 创建或截断文件
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create config file: %v", err)
+// artifactStatus 返回制品列表及各主机的下载记录
+func artifactStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
-	defer file.Close()
+	writeJSON(w, artifactServer.Status())
+}
 
-	// 将配置编码为JSON并写入文件
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ") // 美化输出
-	if err := encoder.Encode(config); err != nil {
-		return fmt.Errorf("failed to encode config to JSON: %v", err)
+// rescanArtifacts 重新扫描制品目录并校验离线包，返回扫描后的状态，校验失败的文件在状态中标出
+func rescanArtifacts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := artifactServer.Scan(); err != nil {
+		log.Printf("Artifact verification failed: %v", err)
+	}
+	writeJSON(w, artifactServer.Status())
+}
+
+// inheritClusterSecrets 重新部署已有集群时沿用之前生成的密钥，避免与主机上的配置不一致
+func inheritClusterSecrets(config *utils.Config) {
+	record, err := clusterStore.Find(*config)
+	if err != nil || record == nil {
+		return
 	}
 
-	return nil
+	if config.Keepalived.AuthPass == "" {
+		config.Keepalived.AuthPass = record.Config.Keepalived.AuthPass
+	}
+}
+
+// setDefaultArtifactsURL 启用制品服务器但未指定地址时，使用 KUBECRAFT_ADVERTISE_ADDRESS 指定的 KubeCraft 地址。
+// 请求的 Host 由浏览器决定，可能是 localhost 或代理地址，集群主机无法访问。
+func setDefaultArtifactsURL(config *utils.Config) {
+	address := os.Getenv("KUBECRAFT_ADVERTISE_ADDRESS")
+	if config.Artifacts.Enabled && config.Artifacts.URL == "" && address != "" {
+		config.Artifacts.URL = fmt.Sprintf("http://%s/artifacts", address)
+		log.Printf("Using artifact URL %s", config.Artifacts.URL)
+	}
 }
@@ -0,0 +1,221 @@
+package addon
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+
+	"KubeCraft/internal/cluster"
+	"KubeCraft/internal/utils"
+)
+
+// ProgressReporter 进度报告接口
+type ProgressReporter interface {
+	ReportProgress(message string)
+}
+
+// Context 附加组件操作所需的集群信息
+type Context struct {
+	Config     utils.Config
+	Kubeconfig string // 为空时使用默认 kubeconfig
+}
+
+// Status 附加组件状态
+type Status struct {
+	Name         string   `json:"name"`
+	Dependencies []string `json:"dependencies"`
+	Enabled      bool     `json:"enabled"`
+	Installed    bool     `json:"installed"`
+	Ready        bool     `json:"ready"`
+	Message      string   `json:"message"`
+}
+
+// Addon 附加组件，安装与升级需可重复执行
+type Addon interface {
+	// Name 组件名称，对应 Config.Addons 中的键
+	Name() string
+	// Dependencies 需要先于本组件安装的组件
+	Dependencies() []string
+	Install(ctx Context) error
+	Upgrade(ctx Context) error
+	Uninstall(ctx Context) error
+	Status(ctx Context) (Status, error)
+}
+
+// registry 按注册顺序保存的附加组件，依赖关系相同时按注册顺序安装
+var registry []Addon
+
+// Register 注册附加组件，名称重复时 panic
+func Register(addon Addon) {
+	if _, ok := Get(addon.Name()); ok {
+		panic(fmt.Sprintf("addon %s is already registered", addon.Name()))
+	}
+	registry = append(registry, addon)
+	utils.RegisterAddonName(addon.Name())
+}
+
+// Get 按名称查找附加组件
+func Get(name string) (Addon, bool) {
+	for _, addon := range registry {
+		if addon.Name() == name {
+			return addon, true
+		}
+	}
+	return nil, false
+}
+
+// All 返回所有已注册的附加组件
+func All() []Addon {
+	return slices.Clone(registry)
+}
+
+// Enabled 返回配置中启用的附加组件，按依赖顺序排列。
+// 被禁用的依赖视为由用户自行提供，只校验依赖是否已注册。
+func Enabled(config utils.Config) ([]Addon, error) {
+	for name := range config.Addons {
+		if _, ok := Get(name); !ok {
+			return nil, fmt.Errorf("addons: unknown addon %q", name)
+		}
+	}
+
+	var ordered []Addon
+	visited := make(map[string]bool)
+	visiting := make(map[string]bool)
+
+	var visit func(addon Addon) error
+	visit = func(addon Addon) error {
+		name := addon.Name()
+		if visited[name] {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("addons: dependency cycle at %s", name)
+		}
+		visiting[name] = true
+		for _, dep := range addon.Dependencies() {
+			depAddon, ok := Get(dep)
+			if !ok {
+				return fmt.Errorf("addons: %s depends on unknown addon %s", name, dep)
+			}
+			if err := visit(depAddon); err != nil {
+				return err
+			}
+		}
+		visiting[name] = false
+		visited[name] = true
+		if config.AddonEnabled(name) {
+			ordered = append(ordered, addon)
+		}
+		return nil
+	}
+
+	for _, addon := range registry {
+		if err := visit(addon); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// Dependents 返回启用的附加组件中直接依赖 name 的组件
+func Dependents(config utils.Config, name string) []string {
+	var dependents []string
+	for _, addon := range registry {
+		if config.AddonEnabled(addon.Name()) && slices.Contains(addon.Dependencies(), name) {
+			dependents = append(dependents, addon.Name())
+		}
+	}
+	return dependents
+}
+
+// InstallAll 按依赖顺序安装所有启用的附加组件
+func InstallAll(ctx Context, reporter ProgressReporter) error {
+	addons, err := Enabled(ctx.Config)
+	if err != nil {
+		return err
+	}
+	for _, addon := range addons {
+		reporter.ReportProgress(fmt.Sprintf("安装 %s...", addon.Name()))
+		log.Printf("Installing addon %s...", addon.Name())
+		if err := addon.Install(ctx); err != nil {
+			return fmt.Errorf("failed to install addon %s: %v", addon.Name(), err)
+		}
+	}
+	return nil
+}
+
+// UpgradeAll 按依赖顺序升级所有启用的附加组件，用于 Kubernetes 升级后更新兼容版本
+func UpgradeAll(ctx Context, reporter ProgressReporter) error {
+	addons, err := Enabled(ctx.Config)
+	if err != nil {
+		return err
+	}
+	for _, addon := range addons {
+		reporter.ReportProgress(fmt.Sprintf("升级 %s...", addon.Name()))
+		log.Printf("Upgrading addon %s...", addon.Name())
+		if err := addon.Upgrade(ctx); err != nil {
+			return fmt.Errorf("failed to upgrade addon %s: %v", addon.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Statuses 返回所有已注册附加组件的状态，查询失败的组件在 Message 中记录错误
+func Statuses(ctx Context) []Status {
+	statuses := make([]Status, 0, len(registry))
+	for _, addon := range registry {
+		status, err := addon.Status(ctx)
+		if err != nil {
+			status.Message = err.Error()
+		}
+		status.Name = addon.Name()
+		status.Dependencies = addon.Dependencies()
+		status.Enabled = ctx.Config.AddonEnabled(addon.Name())
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// SetEnabled 启用并安装或卸载并禁用集群的附加组件，成功后将启用状态保存到集群记录
+func SetEnabled(store *cluster.Store, record *cluster.Record, name string, enabled bool, reporter ProgressReporter) error {
+	addon, ok := Get(name)
+	if !ok {
+		return fmt.Errorf("unknown addon %q", name)
+	}
+
+	config := record.Config
+	config.Addons = maps.Clone(config.Addons)
+	config.SetAddonEnabled(name, enabled)
+	config.ApplyDefaults()
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+	ctx := Context{Config: config, Kubeconfig: store.Kubeconfig(record.ID)}
+
+	if enabled {
+		reporter.ReportProgress(fmt.Sprintf("安装 %s...", name))
+		log.Printf("Installing addon %s on cluster %s", name, record.ID)
+		if err := addon.Install(ctx); err != nil {
+			return fmt.Errorf("failed to install addon %s: %v", name, err)
+		}
+	} else {
+		// 卸载网络插件会中断集群中所有 Pod 的网络
+		if name == utils.AddonCNI && record.Status != cluster.StatusReset {
+			return fmt.Errorf("addon %s cannot be uninstalled from a running cluster", name)
+		}
+		if dependents := Dependents(config, name); len(dependents) > 0 {
+			return fmt.Errorf("addon %s is required by %s", name, strings.Join(dependents, ", "))
+		}
+		reporter.ReportProgress(fmt.Sprintf("卸载 %s...", name))
+		log.Printf("Uninstalling addon %s from cluster %s", name, record.ID)
+		if err := addon.Uninstall(ctx); err != nil {
+			return fmt.Errorf("failed to uninstall addon %s: %v", name, err)
+		}
+	}
+
+	reporter.ReportProgress("保存集群记录...")
+	record.Config = config
+	return store.Save(record)
+}
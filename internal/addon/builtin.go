@@ -0,0 +1,70 @@
+package addon
+
+import (
+	"KubeCraft/internal/utils"
+)
+
+// nfsStorageClass NFS CSI 创建的 StorageClass 名称
+const nfsStorageClass = "nfs-csi"
+
+func init() {
+	Register(&manifestAddon{
+		name:         utils.AddonNfsCsi,
+		dependencies: []string{utils.AddonCNI},
+		files: []string{
+			"csi-nfs-rbac.yaml",
+			"csi-nfs-driverinfo.yaml",
+			"csi-nfs-controller.yaml",
+			"csi-nfs-node.yaml",
+		},
+		workloads: []workload{
+			{kind: "deployment", namespace: "kube-system", name: "csi-nfs-controller"},
+			{kind: "daemonset", namespace: "kube-system", name: "csi-nfs-node"},
+		},
+		postInstall:  applyNfsStorageClass,
+		preUninstall: deleteNfsStorageClass,
+	})
+
+	Register(&manifestAddon{
+		name:         utils.AddonMetalLB,
+		dependencies: []string{utils.AddonCNI},
+		files:        []string{"metallb.yaml"},
+		workloads: []workload{
+			{kind: "deployment", namespace: "metallb-system", name: "controller"},
+			{kind: "daemonset", namespace: "metallb-system", name: "speaker"},
+		},
+		postInstall: applyMetalLBConfig,
+	})
+
+	Register(&manifestAddon{
+		name:         utils.AddonIngressNginx,
+		dependencies: []string{utils.AddonCNI},
+		files:        []string{"ingress-nginx.yaml"},
+		workloads: []workload{
+			{kind: "deployment", namespace: "ingress-nginx", name: "ingress-nginx-controller"},
+		},
+	})
+}
+
+// applyNfsStorageClass 根据 NFS 服务器与共享目录创建 StorageClass，parameters 创建后不可修改，变更时需先删除
+func applyNfsStorageClass(ctx Context) error {
+	return utils.KubectlApply(ctx.Kubeconfig, map[string]any{
+		"apiVersion":  "storage.k8s.io/v1",
+		"kind":        "StorageClass",
+		"metadata":    map[string]any{"name": nfsStorageClass},
+		"provisioner": "nfs.csi.k8s.io",
+		"parameters": map[string]any{
+			"server": ctx.Config.NfsServerIP,
+			"share":  ctx.Config.NfsDir,
+		},
+		"reclaimPolicy":     "Delete",
+		"volumeBindingMode": "Immediate",
+		"mountOptions":      []string{"nfsvers=4.1"},
+	})
+}
+
+// deleteNfsStorageClass 删除 NFS StorageClass，已创建的 PV 不受影响
+func deleteNfsStorageClass(ctx Context) error {
+	_, err := utils.Kubectl(ctx.Kubeconfig, "delete", "storageclass", nfsStorageClass, "--ignore-not-found")
+	return err
+}
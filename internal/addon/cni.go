@@ -0,0 +1,244 @@
+package addon
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"KubeCraft/internal/utils"
+)
+
+// cniRolloutTimeout 等待网络插件就绪的超时时间
+const cniRolloutTimeout = 10 * time.Minute
+
+// cniPlugin 网络插件的 Helm 安装方式
+type cniPlugin struct {
+	release helmRelease
+	ready   workload // 就绪检查的 DaemonSet
+	values  func(config utils.Config) (map[string]any, error)
+}
+
+// cniPlugins 各网络插件的安装方式
+var cniPlugins = map[string]cniPlugin{
+	utils.CNICilium: {
+		release: helmRelease{
+			name: "cilium", chart: "cilium", namespace: "kube-system",
+			repo: "https://helm.cilium.io",
+		},
+		ready:  workload{kind: "daemonset", namespace: "kube-system", name: "cilium"},
+		values: ciliumValues,
+	},
+	// Calico 由 tigera-operator 部署，calico-node 在 operator 就绪后才会创建
+	utils.CNICalico: {
+		release: helmRelease{
+			name: "calico", chart: "tigera-operator", namespace: "tigera-operator",
+			repo: "https://docs.tigera.io/calico/charts",
+		},
+		ready:  workload{kind: "daemonset", namespace: "calico-system", name: "calico-node"},
+		values: calicoValues,
+	},
+	utils.CNIFlannel: {
+		release: helmRelease{
+			name: "flannel", chart: "flannel", namespace: "kube-flannel",
+			repo: "https://flannel-io.github.io/flannel",
+		},
+		ready:  workload{kind: "daemonset", namespace: "kube-flannel", name: "kube-flannel-ds"},
+		values: flannelValues,
+	},
+}
+
+func init() {
+	Register(cniAddon{})
+}
+
+// cniAddon 安装 Config.CNI 选择的网络插件，禁用时需由用户自行安装网络插件
+type cniAddon struct{}
+
+func (cniAddon) Name() string { return utils.AddonCNI }
+
+func (cniAddon) Dependencies() []string { return nil }
+
+// plugin 返回配置所选网络插件的安装方式
+func (cniAddon) plugin(config utils.Config) (cniPlugin, error) {
+	plugin, ok := cniPlugins[config.CNI]
+	if !ok {
+		return cniPlugin{}, fmt.Errorf("unsupported CNI %q", config.CNI)
+	}
+	return plugin, nil
+}
+
+// Install 使用 Helm 安装网络插件，并等待其 DaemonSet 就绪
+func (a cniAddon) Install(ctx Context) error {
+	config := ctx.Config
+	plugin, err := a.plugin(config)
+	if err != nil {
+		return err
+	}
+	version := config.Resolved.CNIVersion
+	if version == "" {
+		return fmt.Errorf("no compatible %s version for Kubernetes %s", config.CNI, config.KubernetesVersion)
+	}
+
+	values, err := plugin.values(config)
+	if err != nil {
+		return err
+	}
+	if err := plugin.release.install(ctx, utils.AddonCNI, version, values); err != nil {
+		return err
+	}
+	log.Printf("%s %s chart installed", config.CNI, version)
+
+	// 网络插件就绪前节点保持 NotReady，后续组件无法调度
+	if err := plugin.ready.wait(ctx.Kubeconfig, cniRolloutTimeout); err != nil {
+		return fmt.Errorf("%s is not ready: %v", config.CNI, err)
+	}
+
+	log.Printf("%s is ready", config.CNI)
+	return nil
+}
+
+// Upgrade 升级到兼容矩阵中目标 Kubernetes 版本对应的网络插件版本
+func (a cniAddon) Upgrade(ctx Context) error {
+	return a.Install(ctx)
+}
+
+func (a cniAddon) Uninstall(ctx Context) error {
+	plugin, err := a.plugin(ctx.Config)
+	if err != nil {
+		return err
+	}
+	return plugin.release.uninstall(ctx)
+}
+
+func (a cniAddon) Status(ctx Context) (Status, error) {
+	plugin, err := a.plugin(ctx.Config)
+	if err != nil {
+		return Status{}, err
+	}
+	return workloadsStatus(ctx.Kubeconfig, []workload{plugin.ready}), nil
+}
+
+// podCIDRs 按地址族拆分 Pod 网段
+func podCIDRs(config utils.Config) (v4, v6 []netip.Prefix, err error) {
+	for _, item := range strings.Split(config.PodNetwork, ",") {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(item))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid podNetwork %q: %v", item, err)
+		}
+		if prefix.Addr().Is4() {
+			v4 = append(v4, prefix.Masked())
+		} else {
+			v6 = append(v6, prefix.Masked())
+		}
+	}
+	return v4, v6, nil
+}
+
+// ciliumValues 根据 Pod 网段、地址族与控制面入口生成 Cilium 的 Helm values
+func ciliumValues(config utils.Config) (map[string]any, error) {
+	v4, v6, err := podCIDRs(config)
+	if err != nil {
+		return nil, err
+	}
+
+	operator := map[string]any{}
+	for _, prefix := range v4 {
+		operator["clusterPoolIPv4PodCIDRList"] = []string{prefix.String()}
+		operator["clusterPoolIPv4MaskSize"] = utils.PodMaskSize(utils.CNICilium, prefix)
+	}
+	for _, prefix := range v6 {
+		operator["clusterPoolIPv6PodCIDRList"] = []string{prefix.String()}
+		operator["clusterPoolIPv6MaskSize"] = utils.PodMaskSize(utils.CNICilium, prefix)
+	}
+
+	values := map[string]any{
+		"ipam": map[string]any{
+			"mode":     "cluster-pool",
+			"operator": operator,
+		},
+		"ipv4": map[string]any{"enabled": len(v4) > 0},
+		"ipv6": map[string]any{"enabled": len(v6) > 0},
+		"operator": map[string]any{
+			"replicas": min(len(config.Masters), 2),
+		},
+		"kubeProxyReplacement": config.KubeProxyMode == utils.KubeProxyNone,
+	}
+
+	// 没有 kube-proxy 时集群内无法通过 kubernetes Service 访问 API Server，需直接指定控制面入口
+	if config.KubeProxyMode == utils.KubeProxyNone {
+		host, port, err := net.SplitHostPort(config.Resolved.ControlPlaneEndpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid control plane endpoint %q: %v", config.Resolved.ControlPlaneEndpoint, err)
+		}
+		values["k8sServiceHost"] = host
+		values["k8sServicePort"] = port
+	}
+
+	return values, nil
+}
+
+// calicoValues 根据 Pod 网段生成 tigera-operator 的 Installation，默认 BGP 加跨子网 VXLAN 封装
+func calicoValues(config utils.Config) (map[string]any, error) {
+	v4, v6, err := podCIDRs(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var ipPools []map[string]any
+	for _, prefix := range slices.Concat(v4, v6) {
+		ipPools = append(ipPools, map[string]any{
+			"cidr":          prefix.String(),
+			"blockSize":     utils.PodMaskSize(utils.CNICalico, prefix),
+			"encapsulation": "VXLANCrossSubnet",
+			"natOutgoing":   "Enabled",
+			"nodeSelector":  "all()",
+		})
+	}
+
+	network := map[string]any{
+		"bgp":     "Enabled",
+		"ipPools": ipPools,
+	}
+	// 多网卡主机上默认会选中第一块网卡，指定网卡避免 BGP 使用错误的地址
+	if config.NetworkAdapter != "" {
+		if len(v4) > 0 {
+			network["nodeAddressAutodetectionV4"] = map[string]any{"interface": config.NetworkAdapter}
+		}
+		if len(v6) > 0 {
+			network["nodeAddressAutodetectionV6"] = map[string]any{"interface": config.NetworkAdapter}
+		}
+	}
+
+	return map[string]any{
+		"installation": map[string]any{
+			"cni":           map[string]any{"type": "Calico"},
+			"calicoNetwork": network,
+		},
+	}, nil
+}
+
+// flannelValues 根据 Pod 网段生成 Flannel 的 Helm values，使用 VXLAN 后端
+func flannelValues(config utils.Config) (map[string]any, error) {
+	v4, v6, err := podCIDRs(config)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]any{}
+	flannel := map[string]any{"backend": "vxlan"}
+	if len(v4) > 0 {
+		values["podCidr"] = v4[0].String()
+	}
+	if len(v6) > 0 {
+		values["podCidrv6"] = v6[0].String()
+	}
+	if config.NetworkAdapter != "" {
+		flannel["args"] = []string{"--ip-masq", "--kube-subnet-mgr", "--iface=" + config.NetworkAdapter}
+	}
+	values["flannel"] = flannel
+	return values, nil
+}
@@ -0,0 +1,108 @@
+package addon
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"KubeCraft/internal/artifact"
+	"KubeCraft/internal/utils"
+)
+
+// ChartDir 离线包中 Helm Chart 所在的制品子目录，文件名为 <chart>-<version>.tgz
+var ChartDir = filepath.Join(artifact.DefaultRoot, "charts")
+
+// helmRelease 通过 Helm 安装的组件
+type helmRelease struct {
+	name      string // release 名称
+	chart     string // Chart 名称
+	namespace string
+	repo      string // 上游 Chart 仓库，离线包中没有该 Chart 且未配置 Mirrors.HelmRepo 时使用
+}
+
+// chartRef 返回 Chart 的安装来源，依次使用离线包中的 Chart、Mirrors.HelmRepo 与上游仓库
+func (r helmRelease) chartRef(config utils.Config, version string) []string {
+	bundled := filepath.Join(ChartDir, fmt.Sprintf("%s-%s.tgz", r.chart, version))
+	if _, err := os.Stat(bundled); err == nil {
+		return []string{bundled}
+	}
+	if config.Mirrors.HelmRepo != "" {
+		return []string{"kubecraft/" + r.chart, "--version", version}
+	}
+	return []string{r.chart, "--repo", r.repo, "--version", version}
+}
+
+// helm 执行 helm 命令并返回输出
+func helm(kubeconfig string, args ...string) (string, error) {
+	if kubeconfig != "" {
+		args = append([]string{"--kubeconfig", kubeconfig}, args...)
+	}
+
+	cmd := exec.Command("helm", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("helm %v failed: %v, output: %s", args, err, string(output))
+	}
+	return string(output), nil
+}
+
+// install 安装或升级 release，values 会被 Config.Addons 中该组件的 Values 覆盖
+func (r helmRelease) install(ctx Context, addon, version string, values map[string]any) error {
+	chart := r.chartRef(ctx.Config, version)
+	merged := mergeValues(values, ctx.Config.Addons[addon].Values)
+	valuesFile, err := os.CreateTemp("", "kubecraft-"+addon+"-values-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create values file: %v", err)
+	}
+	defer os.Remove(valuesFile.Name())
+	err = json.NewEncoder(valuesFile).Encode(merged)
+	valuesFile.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write values file: %v", err)
+	}
+
+	args := append([]string{"upgrade", "--install", r.name}, chart...)
+	args = append(args, "--namespace", r.namespace, "--create-namespace", "--values", valuesFile.Name())
+	_, err = helm(ctx.Kubeconfig, args...)
+	return err
+}
+
+// uninstall 卸载 release，未安装时忽略
+func (r helmRelease) uninstall(ctx Context) error {
+	installed, err := r.installed(ctx)
+	if err != nil || !installed {
+		return err
+	}
+	_, err = helm(ctx.Kubeconfig, "uninstall", r.name, "--namespace", r.namespace, "--wait")
+	return err
+}
+
+// installed 返回 release 是否已安装
+func (r helmRelease) installed(ctx Context) (bool, error) {
+	output, err := helm(ctx.Kubeconfig, "list", "--namespace", r.namespace, "--filter", "^"+r.name+"$", "--short")
+	if err != nil {
+		return false, err
+	}
+	return output != "", nil
+}
+
+// mergeValues 将 override 递归合并到 base 的副本中，嵌套的 map 逐键合并，其余类型直接覆盖
+func mergeValues(base, override map[string]any) map[string]any {
+	merged := maps.Clone(base)
+	if merged == nil {
+		merged = make(map[string]any)
+	}
+	for key, value := range override {
+		baseMap, ok1 := merged[key].(map[string]any)
+		overrideMap, ok2 := value.(map[string]any)
+		if ok1 && ok2 {
+			merged[key] = mergeValues(baseMap, overrideMap)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
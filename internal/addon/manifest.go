@@ -0,0 +1,80 @@
+package addon
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"KubeCraft/internal/utils"
+)
+
+// ManifestDir 附加组件 manifest 所在目录
+const ManifestDir = "./yaml"
+
+// manifestRolloutTimeout 等待 manifest 中工作负载就绪的超时时间
+const manifestRolloutTimeout = 5 * time.Minute
+
+// manifestAddon 通过 kubectl apply 安装的附加组件
+type manifestAddon struct {
+	name         string
+	dependencies []string
+	files        []string   // 按顺序 apply 的 manifest，位于 ManifestDir
+	workloads    []workload // 就绪检查的工作负载
+	// postInstall 在工作负载就绪后执行，用于创建依赖集群配置或 webhook 的资源
+	postInstall func(ctx Context) error
+	// preUninstall 在删除 manifest 之前执行，清理 postInstall 创建的资源
+	preUninstall func(ctx Context) error
+}
+
+func (a *manifestAddon) Name() string { return a.name }
+
+func (a *manifestAddon) Dependencies() []string { return a.dependencies }
+
+func (a *manifestAddon) Install(ctx Context) error {
+	for _, file := range a.files {
+		path := filepath.Join(ManifestDir, file)
+		if _, err := utils.Kubectl(ctx.Kubeconfig, "apply", "-f", path); err != nil {
+			return fmt.Errorf("failed to apply %s: %v", path, err)
+		}
+		log.Printf("Applied %s", path)
+	}
+
+	for _, w := range a.workloads {
+		if err := w.wait(ctx.Kubeconfig, manifestRolloutTimeout); err != nil {
+			return fmt.Errorf("%s is not ready: %v", w, err)
+		}
+	}
+
+	if a.postInstall != nil {
+		return a.postInstall(ctx)
+	}
+	return nil
+}
+
+// Upgrade manifest 随 KubeCraft 更新，重新 apply 即可
+func (a *manifestAddon) Upgrade(ctx Context) error {
+	return a.Install(ctx)
+}
+
+func (a *manifestAddon) Uninstall(ctx Context) error {
+	if a.preUninstall != nil {
+		if err := a.preUninstall(ctx); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range slices.Backward(a.files) {
+		path := filepath.Join(ManifestDir, file)
+		if _, err := utils.Kubectl(ctx.Kubeconfig, "delete", "-f", path, "--ignore-not-found"); err != nil {
+			return fmt.Errorf("failed to delete %s: %v", path, err)
+		}
+		log.Printf("Deleted %s", path)
+	}
+	return nil
+}
+
+func (a *manifestAddon) Status(ctx Context) (Status, error) {
+	return workloadsStatus(ctx.Kubeconfig, a.workloads), nil
+}
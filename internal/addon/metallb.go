@@ -0,0 +1,131 @@
+package addon
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"KubeCraft/internal/utils"
+)
+
+// metalLBNamespace MetalLB 所在命名空间
+const metalLBNamespace = "metallb-system"
+
+// metalLBWebhookTimeout 等待 MetalLB webhook 接受配置的超时时间
+const metalLBWebhookTimeout = 2 * time.Minute
+
+// metalLBManagedLabel 标记由 KubeCraft 生成的 MetalLB 资源，用于清理已从配置中移除的资源
+const metalLBManagedLabel = "app.kubernetes.io/managed-by=kubecraft"
+
+// metalLBKinds 由 KubeCraft 生成的 MetalLB 资源类型
+var metalLBKinds = []string{"ipaddresspools", "l2advertisements", "bgpadvertisements", "bgppeers"}
+
+// metalLBResources 根据 Resolved.MetalLBPools 与宣告方式生成 IPAddressPool、L2Advertisement 或 BGPPeer 与 BGPAdvertisement
+func metalLBResources(config utils.Config) []map[string]any {
+	metadata := func(name string) map[string]any {
+		return map[string]any{
+			"name":      name,
+			"namespace": metalLBNamespace,
+			"labels":    map[string]any{"app.kubernetes.io/managed-by": "kubecraft"},
+		}
+	}
+
+	var resources []map[string]any
+	var poolNames []string
+	for _, pool := range config.Resolved.MetalLBPools {
+		autoAssign := pool.AutoAssign == nil || *pool.AutoAssign
+		resources = append(resources, map[string]any{
+			"apiVersion": "metallb.io/v1beta1",
+			"kind":       "IPAddressPool",
+			"metadata":   metadata(pool.Name),
+			"spec": map[string]any{
+				"addresses":  pool.Addresses,
+				"autoAssign": autoAssign,
+			},
+		})
+		poolNames = append(poolNames, pool.Name)
+	}
+	if len(poolNames) == 0 {
+		return resources
+	}
+
+	if config.MetalLB.Mode == utils.MetalLBModeL2 {
+		return append(resources, map[string]any{
+			"apiVersion": "metallb.io/v1beta1",
+			"kind":       "L2Advertisement",
+			"metadata":   metadata("kubecraft"),
+			"spec":       map[string]any{"ipAddressPools": poolNames},
+		})
+	}
+
+	for _, peer := range config.MetalLB.Peers {
+		spec := map[string]any{
+			"peerAddress": peer.Address,
+			"peerASN":     peer.ASN,
+			"myASN":       peer.MyASN,
+			"peerPort":    peer.Port,
+		}
+		if peer.Password != "" {
+			spec["password"] = peer.Password
+		}
+		resources = append(resources, map[string]any{
+			"apiVersion": "metallb.io/v1beta2",
+			"kind":       "BGPPeer",
+			"metadata":   metadata(peer.Name),
+			"spec":       spec,
+		})
+	}
+	return append(resources, map[string]any{
+		"apiVersion": "metallb.io/v1beta1",
+		"kind":       "BGPAdvertisement",
+		"metadata":   metadata("kubecraft"),
+		"spec":       map[string]any{"ipAddressPools": poolNames},
+	})
+}
+
+// applyMetalLBConfig 创建地址池与宣告配置，并删除已从配置中移除的资源。
+// controller 就绪后 webhook 证书可能尚未注入，apply 失败时重试直到超时。
+func applyMetalLBConfig(ctx Context) error {
+	resources := metalLBResources(ctx.Config)
+	if len(resources) > 0 {
+		list := map[string]any{"apiVersion": "v1", "kind": "List", "items": resources}
+		deadline := time.Now().Add(metalLBWebhookTimeout)
+		for {
+			err := utils.KubectlApply(ctx.Kubeconfig, list)
+			if err == nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("failed to apply MetalLB address pools: %v", err)
+			}
+			log.Printf("MetalLB webhook is not ready, retrying: %v", err)
+			time.Sleep(5 * time.Second)
+		}
+		log.Printf("MetalLB %s configuration applied with %d pools", ctx.Config.MetalLB.Mode, len(ctx.Config.Resolved.MetalLBPools))
+	}
+
+	// 清理不再属于当前配置的资源，如切换宣告方式或删除地址池
+	var desired []string
+	for _, resource := range resources {
+		kind := strings.ToLower(resource["kind"].(string))
+		name := resource["metadata"].(map[string]any)["name"].(string)
+		desired = append(desired, kind+".metallb.io/"+name)
+	}
+	output, err := utils.Kubectl(ctx.Kubeconfig, "-n", metalLBNamespace, "get", strings.Join(metalLBKinds, ","),
+		"-l", metalLBManagedLabel, "-o", "name")
+	if err != nil {
+		return err
+	}
+	for _, existing := range strings.Fields(output) {
+		if slices.Contains(desired, existing) {
+			continue
+		}
+		if _, err := utils.Kubectl(ctx.Kubeconfig, "-n", metalLBNamespace, "delete", existing, "--ignore-not-found"); err != nil {
+			return err
+		}
+		log.Printf("Deleted stale MetalLB resource %s", existing)
+	}
+	return nil
+}
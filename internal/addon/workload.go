@@ -0,0 +1,76 @@
+package addon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"KubeCraft/internal/utils"
+)
+
+// workload 用于就绪检查的工作负载
+type workload struct {
+	kind      string // daemonset 或 deployment
+	namespace string
+	name      string
+}
+
+func (w workload) String() string {
+	return fmt.Sprintf("%s %s/%s", w.kind, w.namespace, w.name)
+}
+
+// wait 等待工作负载创建并完成滚动更新
+func (w workload) wait(kubeconfig string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	// 由 operator 创建的工作负载在 Chart 安装完成时可能还不存在
+	for {
+		_, err := utils.Kubectl(kubeconfig, "-n", w.namespace, "get", w.kind, w.name)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s was not created within %s", w, timeout)
+		}
+		time.Sleep(5 * time.Second)
+	}
+
+	remaining := max(time.Until(deadline).Round(time.Second), time.Second)
+	_, err := utils.Kubectl(kubeconfig, "-n", w.namespace, "rollout", "status", w.kind+"/"+w.name, "--timeout="+remaining.String())
+	return err
+}
+
+// status 返回工作负载是否存在以及就绪副本数
+func (w workload) status(kubeconfig string) (installed, ready bool, message string) {
+	jsonpath := "{.status.readyReplicas}/{.spec.replicas}"
+	if w.kind == "daemonset" {
+		jsonpath = "{.status.numberReady}/{.status.desiredNumberScheduled}"
+	}
+	output, err := utils.Kubectl(kubeconfig, "-n", w.namespace, "get", w.kind, w.name, "-o", "jsonpath="+jsonpath)
+	if err != nil {
+		if strings.Contains(output, "NotFound") {
+			return false, false, fmt.Sprintf("%s not found", w)
+		}
+		return false, false, err.Error()
+	}
+
+	readyCount, desired, _ := strings.Cut(strings.TrimSpace(output), "/")
+	if readyCount == "" {
+		readyCount = "0"
+	}
+	return true, readyCount == desired && desired != "0", fmt.Sprintf("%s %s/%s ready", w, readyCount, desired)
+}
+
+// workloadsStatus 汇总多个工作负载的状态
+func workloadsStatus(kubeconfig string, workloads []workload) Status {
+	status := Status{Installed: true, Ready: true}
+	var messages []string
+	for _, w := range workloads {
+		installed, ready, message := w.status(kubeconfig)
+		status.Installed = status.Installed && installed
+		status.Ready = status.Ready && ready
+		messages = append(messages, message)
+	}
+	status.Message = strings.Join(messages, "; ")
+	return status
+}
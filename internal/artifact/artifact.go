@@ -0,0 +1,287 @@
+package artifact
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"KubeCraft/internal/utils"
+)
+
+// DefaultRoot 制品目录的默认位置
+//
+// 目录约定：
+//
+//	repo/    yum 仓库（已执行 createrepo）
+//	bin/     二进制包，如 cri-containerd-cni-*.tar.gz
+//	images/  镜像 tar 包，部署时导入到 containerd
+//	charts/  Helm Chart，如 cilium-1.14.5.tgz，KubeCraft 安装网络插件时直接使用
+//
+// 每个文件需列在所在目录的 SHA256SUMS 中，未通过校验的文件不提供下载
+const DefaultRoot = "./artifacts"
+
+// ChecksumFile 每个目录下的校验文件名，格式与 sha256sum 输出一致
+const ChecksumFile = "SHA256SUMS"
+
+// 下载记录上限：每台主机只保留最近的记录，主机数超过上限时丢弃最久未下载的主机
+const (
+	maxDownloadsPerHost = 200
+	maxDownloadHosts    = 256
+)
+
+// File 制品文件信息
+type File struct {
+	Path     string `json:"path"`     // 相对于制品目录的路径
+	Size     int64  `json:"size"`     // 文件大小
+	SHA256   string `json:"sha256"`   // 计算得到的校验值
+	Expected string `json:"expected"` // 离线包自带的 SHA256SUMS 中的校验值
+	Verified bool   `json:"verified"` // 是否与离线包自带的 SHA256SUMS 一致
+	Error    string `json:"error"`    // 校验失败原因
+}
+
+// Download 主机下载记录
+type Download struct {
+	Path   string    `json:"path"`
+	Bytes  int64     `json:"bytes"`
+	Status int       `json:"status"`
+	Time   time.Time `json:"time"`
+}
+
+// Status 制品服务器状态
+type Status struct {
+	Root      string                `json:"root"`
+	Files     []File                `json:"files"`
+	Downloads map[string][]Download `json:"downloads"` // 按主机 IP 分组
+}
+
+// Server 提供制品的 HTTP 服务，并记录每台主机的下载情况
+type Server struct {
+	root      string
+	scanMu    sync.Mutex // 串行执行扫描，避免并发计算整个离线包的校验值
+	mu        sync.Mutex
+	files     map[string]File
+	downloads map[string][]Download
+}
+
+// NewServer 创建制品服务器
+func NewServer(root string) *Server {
+	return &Server{
+		root:      root,
+		files:     make(map[string]File),
+		downloads: make(map[string][]Download),
+	}
+}
+
+// Scan 扫描制品目录并计算校验值，离线包自带的 SHA256SUMS 用于校验文件完整性
+func (s *Server) Scan() error {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+
+	files := make(map[string]File)
+	expected := make(map[string]string)
+
+	err := filepath.Walk(s.root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if path.Base(rel) == ChecksumFile {
+			return readChecksumFile(p, path.Dir(rel), expected)
+		}
+
+		sum, err := utils.FileSHA256(p)
+		if err != nil {
+			return fmt.Errorf("failed to checksum %s: %v", rel, err)
+		}
+		files[rel] = File{Path: rel, Size: info.Size(), SHA256: sum}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		log.Printf("Artifact directory %s does not exist, serving nothing", s.root)
+	} else if err != nil {
+		return fmt.Errorf("failed to scan artifact directory: %v", err)
+	}
+
+	var mismatched []string
+	for rel, file := range files {
+		want, ok := expected[rel]
+		file.Expected = want
+		switch {
+		case !ok:
+			file.Error = "no checksum in bundle"
+		case want != file.SHA256:
+			file.Error = fmt.Sprintf("checksum mismatch, expected %s", want)
+			mismatched = append(mismatched, rel)
+		default:
+			file.Verified = true
+		}
+		files[rel] = file
+	}
+
+	s.mu.Lock()
+	s.files = files
+	s.mu.Unlock()
+
+	if len(mismatched) > 0 {
+		sort.Strings(mismatched)
+		return fmt.Errorf("artifact checksum mismatch: %s", strings.Join(mismatched, ", "))
+	}
+	return nil
+}
+
+// ServeHTTP 提供通过校验的制品下载，目录下的 SHA256SUMS 根据扫描结果动态生成
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rel := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+
+	s.mu.Lock()
+	file := s.files[rel]
+	s.mu.Unlock()
+
+	recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	switch {
+	case path.Base(rel) == ChecksumFile:
+		s.serveChecksums(recorder, r, path.Dir(rel))
+	case !file.Verified:
+		http.NotFound(recorder, r)
+	default:
+		http.ServeFile(recorder, r, filepath.Join(s.root, filepath.FromSlash(rel)))
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	s.record(host, Download{
+		Path:   rel,
+		Bytes:  recorder.bytes,
+		Status: recorder.status,
+		Time:   time.Now(),
+	})
+}
+
+// record 保存主机的下载记录，超出上限时丢弃最旧的记录
+func (s *Server) record(host string, download Download) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.downloads[host]; !ok && len(s.downloads) >= maxDownloadHosts {
+		var oldest string
+		for h, downloads := range s.downloads {
+			if oldest == "" || downloads[len(downloads)-1].Time.Before(s.downloads[oldest][len(s.downloads[oldest])-1].Time) {
+				oldest = h
+			}
+		}
+		delete(s.downloads, oldest)
+	}
+
+	downloads := append(s.downloads[host], download)
+	if len(downloads) > maxDownloadsPerHost {
+		downloads = slices.Clone(downloads[len(downloads)-maxDownloadsPerHost:])
+	}
+	s.downloads[host] = downloads
+}
+
+// Status 返回制品列表与各主机的下载记录
+func (s *Server) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := Status{
+		Root:      s.root,
+		Files:     make([]File, 0, len(s.files)),
+		Downloads: make(map[string][]Download, len(s.downloads)),
+	}
+	for _, file := range s.files {
+		status.Files = append(status.Files, file)
+	}
+	sort.Slice(status.Files, func(i, j int) bool {
+		return status.Files[i].Path < status.Files[j].Path
+	})
+	for host, downloads := range s.downloads {
+		status.Downloads[host] = append([]Download(nil), downloads...)
+	}
+	return status
+}
+
+// serveChecksums 输出指定目录下通过校验的文件在离线包 SHA256SUMS 中的校验值，
+// 扫描后被修改的文件下载时会校验失败
+func (s *Server) serveChecksums(w http.ResponseWriter, r *http.Request, dir string) {
+	s.mu.Lock()
+	var lines []string
+	for rel, file := range s.files {
+		if file.Verified && path.Dir(rel) == dir {
+			lines = append(lines, fmt.Sprintf("%s  %s\n", file.Expected, path.Base(rel)))
+		}
+	}
+	s.mu.Unlock()
+
+	if len(lines) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	sort.Strings(lines)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, line := range lines {
+		_, err := io.WriteString(w, line)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readChecksumFile 读取 sha256sum 格式的校验文件
+func readChecksumFile(p, dir string, expected map[string]string) error {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(fields[1], "*"), "./")
+		expected[path.Join(dir, name)] = strings.ToLower(fields[0])
+	}
+	return nil
+}
+
+// responseRecorder 记录响应状态码与发送字节数
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
@@ -0,0 +1,221 @@
+package backup
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"KubeCraft/internal/cluster"
+	"KubeCraft/internal/etcd"
+	"KubeCraft/internal/utils"
+)
+
+// 备份策略默认值
+const (
+	DefaultIntervalHours = 24
+	DefaultRetention     = 7
+)
+
+// ProgressReporter 进度报告接口
+type ProgressReporter interface {
+	ReportProgress(message string)
+}
+
+// Dir 返回集群快照的保存目录
+func Dir(store *cluster.Store, id string) string {
+	return filepath.Join(store.Dir(id), "backups")
+}
+
+// List 返回集群的快照列表，从新到旧排序
+func List(store *cluster.Store, record *cluster.Record) ([]etcd.Snapshot, error) {
+	return etcd.ListSnapshots(Dir(store, record.ID))
+}
+
+// Snapshot 从健康的 Master 生成 etcd 快照，保存到 KubeCraft 主机并按保留策略清理旧快照
+func Snapshot(store *cluster.Store, record *cluster.Record) (etcd.Snapshot, error) {
+	config := record.Config
+	if err := config.WriteAnsibleFiles(); err != nil {
+		return etcd.Snapshot{}, err
+	}
+
+	client, err := healthyMember(store.Kubeconfig(record.ID), store.EtcdPKIDir(record.ID), config)
+	if err != nil {
+		return etcd.Snapshot{}, err
+	}
+
+	log.Printf("Taking etcd snapshot of cluster %s on %s", record.ID, client.Host)
+	dir := Dir(store, record.ID)
+	snapshot, err := client.SaveSnapshot(dir)
+	if err != nil {
+		return snapshot, err
+	}
+
+	retention := record.Backup.Retention
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	if err := etcd.PruneSnapshots(dir, retention); err != nil {
+		log.Printf("Failed to prune snapshots of cluster %s: %v", record.ID, err)
+	}
+
+	log.Printf("etcd snapshot %s of cluster %s saved", snapshot.Name, record.ID)
+	return snapshot, nil
+}
+
+// Restore 将指定快照恢复到所有 Master。恢复会回滚快照之后的所有集群变更，期间 API Server 不可用。
+func Restore(store *cluster.Store, record *cluster.Record, name string, reporter ProgressReporter) error {
+	// 恢复流程基于 Master 上的 etcd 静态 Pod
+	if record.Config.IsExternalEtcd() {
+		return fmt.Errorf("restoring external etcd is not supported, restore snapshot %s on the etcd hosts with etcdutl", name)
+	}
+
+	log.Printf("Restoring etcd snapshot %s of cluster %s", name, record.ID)
+	dir := Dir(store, record.ID)
+
+	reporter.ReportProgress(fmt.Sprintf("校验快照 %s...", name))
+	snapshot, err := etcd.VerifySnapshot(dir, name)
+	if err != nil {
+		return err
+	}
+	if !snapshot.Verified {
+		return fmt.Errorf("snapshot %s failed verification: %s", name, snapshot.Error)
+	}
+
+	reporter.ReportProgress("生成 Ansible inventory 与配置文件...")
+	if err := record.Config.WriteAnsibleFiles(); err != nil {
+		return err
+	}
+
+	reporter.ReportProgress("停止 etcd 与 API Server 并恢复快照...")
+	if err := etcd.RestoreSnapshot(dir, name); err != nil {
+		return err
+	}
+
+	reporter.ReportProgress("检查 etcd 集群健康状态...")
+	client, err := healthyMember(store.Kubeconfig(record.ID), store.EtcdPKIDir(record.ID), record.Config)
+	if err != nil {
+		return err
+	}
+	members, err := client.Members()
+	if err != nil {
+		return err
+	}
+	if err := etcd.CheckAddQuorum(members); err != nil {
+		return fmt.Errorf("etcd is unhealthy after restore: %v", err)
+	}
+
+	log.Printf("etcd snapshot %s of cluster %s restored", name, record.ID)
+	return nil
+}
+
+// Scheduler 按集群的备份策略定时生成快照
+type Scheduler struct {
+	store *cluster.Store
+}
+
+// NewScheduler 创建定时备份调度器
+func NewScheduler(store *cluster.Store) *Scheduler {
+	return &Scheduler{store: store}
+}
+
+// Start 在后台定时检查各集群是否需要备份
+func (s *Scheduler) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			s.runDue()
+		}
+	}()
+}
+
+// runDue 为到期的集群生成快照，集群正在执行其他操作时推迟到下次检查
+func (s *Scheduler) runDue() {
+	records, err := s.store.List()
+	if err != nil {
+		log.Printf("Failed to list clusters for backup: %v", err)
+		return
+	}
+
+	for _, record := range records {
+		if !record.Backup.Enabled || record.Status != cluster.StatusReady || !s.due(record) {
+			continue
+		}
+
+		unlock, err := s.store.TryLock(record.ID)
+		if err != nil {
+			log.Printf("Skipping scheduled backup of cluster %s: %v", record.ID, err)
+			continue
+		}
+		if _, err := Snapshot(s.store, record); err != nil {
+			log.Printf("Scheduled backup of cluster %s failed: %v", record.ID, err)
+		}
+		unlock()
+	}
+}
+
+// due 判断距离最近一次快照是否已超过备份间隔
+func (s *Scheduler) due(record *cluster.Record) bool {
+	snapshots, err := List(s.store, record)
+	if err != nil {
+		log.Printf("Failed to list snapshots of cluster %s: %v", record.ID, err)
+		return false
+	}
+	if len(snapshots) == 0 {
+		return true
+	}
+
+	hours := record.Backup.IntervalHours
+	if hours <= 0 {
+		hours = DefaultIntervalHours
+	}
+	return time.Since(snapshots[0].CreatedAt) >= time.Duration(hours)*time.Hour
+}
+
+// healthyMember 返回可执行 etcdctl 的健康 Master，优先使用首个 Master，首个 Master 不可用时依次通过其余 Master 查询成员。
+// 外部 etcd 由 etcdctl 自行选择健康的成员。
+func healthyMember(kubeconfig, pkiDir string, config utils.Config) (*etcd.Client, error) {
+	if config.IsExternalEtcd() {
+		client := etcd.NewClient(kubeconfig, config, config.FirstMasterHostname, pkiDir)
+		members, err := client.Members()
+		if err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(members, func(member etcd.Member) bool { return member.Healthy }) {
+			return client, nil
+		}
+		return nil, fmt.Errorf("no healthy etcd member found")
+	}
+
+	hosts := slices.Sorted(maps.Keys(config.Masters))
+	if i := slices.Index(hosts, config.FirstMasterHostname); i > 0 {
+		hosts = slices.Insert(slices.Delete(hosts, i, i+1), 0, config.FirstMasterHostname)
+	}
+
+	var members []etcd.Member
+	var lastErr error
+	for _, host := range hosts {
+		client := &etcd.Client{Kubeconfig: kubeconfig, Host: host}
+		list, err := client.Members()
+		if err == nil {
+			members, lastErr = list, nil
+			break
+		}
+		log.Printf("Failed to query etcd on %s: %v", host, err)
+		lastErr = err
+	}
+	if lastErr != nil {
+		return nil, fmt.Errorf("no reachable etcd member: %v", lastErr)
+	}
+
+	for _, host := range hosts {
+		if member, ok := etcd.FindMember(members, host); ok && member.Healthy {
+			return &etcd.Client{Kubeconfig: kubeconfig, Host: host}, nil
+		}
+	}
+	return nil, fmt.Errorf("no healthy etcd member found")
+}
@@ -0,0 +1,145 @@
+package certs
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"KubeCraft/internal/cluster"
+	"KubeCraft/internal/deploy"
+	"KubeCraft/internal/utils"
+)
+
+// ProgressReporter 进度报告接口
+type ProgressReporter interface {
+	ReportProgress(message string)
+}
+
+// Certificate 证书过期信息
+type Certificate struct {
+	Name              string    `json:"name"`
+	Authority         bool      `json:"authority"` // 是否为 CA 证书
+	ExpirationDate    time.Time `json:"expirationDate"`
+	DaysRemaining     int       `json:"daysRemaining"`
+	ExternallyManaged bool      `json:"externallyManaged"`
+	Missing           bool      `json:"missing"`
+}
+
+// HostCertificates 单个 Master 的证书列表
+type HostCertificates struct {
+	Host         string        `json:"host"`
+	Certificates []Certificate `json:"certificates"`
+}
+
+// expirationInfo kubeadm certs check-expiration -o json 的输出
+type expirationInfo struct {
+	Certificates []struct {
+		Name              string    `json:"name"`
+		ExpirationDate    time.Time `json:"expirationDate"`
+		ExternallyManaged bool      `json:"externallyManaged"`
+		Missing           bool      `json:"missing"`
+	} `json:"certificates"`
+	CertificateAuthorities []struct {
+		Name              string    `json:"name"`
+		ExpirationDate    time.Time `json:"expirationDate"`
+		ExternallyManaged bool      `json:"externallyManaged"`
+		Missing           bool      `json:"missing"`
+	} `json:"certificateAuthorities"`
+}
+
+// Check 在每台 Master 上执行 kubeadm certs check-expiration，返回各证书的剩余天数
+func Check(store *cluster.Store, record *cluster.Record) ([]HostCertificates, error) {
+	config := record.Config
+	if err := config.WriteAnsibleFiles(); err != nil {
+		return nil, err
+	}
+
+	outputDir, err := filepath.Abs(filepath.Join(store.Dir(record.ID), "certs"))
+	if err != nil {
+		return nil, err
+	}
+	if err := os.RemoveAll(outputDir); err != nil {
+		return nil, fmt.Errorf("failed to clean certificate report directory: %v", err)
+	}
+	if err := os.MkdirAll(outputDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create certificate report directory: %v", err)
+	}
+
+	if err := utils.ExecuteAnsiblePlaybook("certsCheckExpiration", "-e", "output_dir="+outputDir); err != nil {
+		return nil, fmt.Errorf("failed to check certificate expiration: %v", err)
+	}
+
+	now := time.Now()
+	var hosts []HostCertificates
+	for _, host := range slices.Sorted(maps.Keys(config.Masters)) {
+		data, err := os.ReadFile(filepath.Join(outputDir, host+".json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read certificate report of %s: %v", host, err)
+		}
+
+		var info expirationInfo
+		if err := json.Unmarshal(data, &info); err != nil {
+			return nil, fmt.Errorf("failed to decode certificate report of %s: %v", host, err)
+		}
+
+		result := HostCertificates{Host: host}
+		for _, cert := range info.CertificateAuthorities {
+			result.Certificates = append(result.Certificates, Certificate{
+				Name:              cert.Name,
+				Authority:         true,
+				ExpirationDate:    cert.ExpirationDate,
+				DaysRemaining:     daysUntil(now, cert.ExpirationDate),
+				ExternallyManaged: cert.ExternallyManaged,
+				Missing:           cert.Missing,
+			})
+		}
+		for _, cert := range info.Certificates {
+			result.Certificates = append(result.Certificates, Certificate{
+				Name:              cert.Name,
+				ExpirationDate:    cert.ExpirationDate,
+				DaysRemaining:     daysUntil(now, cert.ExpirationDate),
+				ExternallyManaged: cert.ExternallyManaged,
+				Missing:           cert.Missing,
+			})
+		}
+		hosts = append(hosts, result)
+	}
+	return hosts, nil
+}
+
+// Renew 逐台 Master 续期证书并重启控制面组件，等待 etcd 与 API Server 恢复后继续，然后刷新各主机与集群记录中的 kubeconfig
+func Renew(store *cluster.Store, record *cluster.Record, reporter ProgressReporter) error {
+	log.Printf("Renewing certificates of cluster %s", record.ID)
+
+	reporter.ReportProgress("生成 Ansible inventory 与配置文件...")
+	if err := record.Config.WriteAnsibleFiles(); err != nil {
+		return err
+	}
+
+	reporter.ReportProgress("执行certsRenew...")
+	if err := utils.ExecuteAnsiblePlaybook("certsRenew"); err != nil {
+		return fmt.Errorf("failed to renew certificates: %v", err)
+	}
+
+	reporter.ReportProgress("保存集群 kubeconfig...")
+	if err := deploy.FetchKubeconfig(store.KubeconfigPath(record.ID)); err != nil {
+		return err
+	}
+	if err := store.Save(record); err != nil {
+		return err
+	}
+
+	log.Printf("Certificates of cluster %s renewed", record.ID)
+	return nil
+}
+
+// daysUntil 返回距离过期的天数，已过期时为负数
+func daysUntil(now, expiration time.Time) int {
+	return int(math.Floor(expiration.Sub(now).Hours() / 24))
+}
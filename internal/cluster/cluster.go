@@ -0,0 +1,241 @@
+package cluster
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"KubeCraft/internal/utils"
+)
+
+// DefaultRoot 集群记录的默认保存目录，每个集群一个子目录
+const DefaultRoot = "./clusters"
+
+// 集群状态
+const (
+	StatusDeploying = "deploying"
+	StatusReady     = "ready"
+	StatusFailed    = "failed"
+	StatusUpgrading = "upgrading"
+	StatusReset     = "reset"
+)
+
+// Record 集群记录，保存部署时使用的配置以及后续运维操作的结果
+type Record struct {
+	ID        string       `json:"id"`
+	Status    string       `json:"status"`
+	Config    utils.Config `json:"config"`
+	Upgrades  []Upgrade    `json:"upgrades"`
+	Backup    BackupPolicy `json:"backup"`
+	CreatedAt time.Time    `json:"createdAt"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+}
+
+// BackupPolicy etcd 定时备份策略
+type BackupPolicy struct {
+	Enabled       bool `json:"enabled"`
+	IntervalHours int  `json:"intervalHours"` // 备份间隔，默认 24 小时
+	Retention     int  `json:"retention"`     // 保留的快照数量，默认 7
+}
+
+// Upgrade Kubernetes 版本升级记录
+type Upgrade struct {
+	PreviousVersion string    `json:"previousVersion"`
+	Version         string    `json:"version"`
+	Status          string    `json:"status"` // upgrading、ready 或 failed
+	Error           string    `json:"error"`
+	StartedAt       time.Time `json:"startedAt"`
+	FinishedAt      time.Time `json:"finishedAt"`
+}
+
+// Store 基于文件的集群记录存储
+type Store struct {
+	root string
+	mu   sync.Mutex
+	busy string // 正在执行操作的集群 ID
+}
+
+// NewStore 创建集群记录存储
+func NewStore(root string) *Store {
+	return &Store{root: root}
+}
+
+// Dir 返回集群的数据目录
+func (s *Store) Dir(id string) string {
+	return filepath.Join(s.root, id)
+}
+
+// EtcdPKIDir 返回集群外部 etcd 证书的保存目录
+func (s *Store) EtcdPKIDir(id string) string {
+	return filepath.Join(s.Dir(id), "etcd-pki")
+}
+
+// KubeconfigPath 返回集群 admin kubeconfig 的保存路径
+func (s *Store) KubeconfigPath(id string) string {
+	return filepath.Join(s.Dir(id), "admin.conf")
+}
+
+// Kubeconfig 返回集群 admin kubeconfig 路径，尚未保存时返回空字符串以使用默认 kubeconfig
+func (s *Store) Kubeconfig(id string) string {
+	path := s.KubeconfigPath(id)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// Get 读取集群记录
+func (s *Store) Get(id string) (*Record, error) {
+	if id == "" || filepath.Base(id) != id {
+		return nil, fmt.Errorf("invalid cluster id %q", id)
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.Dir(id), "cluster.json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("cluster %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster %s: %v", id, err)
+	}
+
+	var record Record
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode cluster %s: %v", id, err)
+	}
+	return &record, nil
+}
+
+// List 列出所有集群记录，按创建时间排序
+func (s *Store) List() ([]*Record, error) {
+	entries, err := os.ReadDir(s.root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %v", err)
+	}
+
+	var records []*Record
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		record, err := s.Get(entry.Name())
+		if err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+	return records, nil
+}
+
+// Save 保存集群记录，记录中包含 SSH 密码，文件仅 root 可读
+func (s *Store) Save(record *Record) error {
+	now := time.Now()
+	if record.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		record.ID = id
+		record.CreatedAt = now
+	}
+	record.UpdatedAt = now
+
+	if err := os.MkdirAll(s.Dir(record.ID), 0700); err != nil {
+		return fmt.Errorf("failed to create cluster directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cluster %s: %v", record.ID, err)
+	}
+
+	// 记录中包含集群配置的凭据，os.WriteFile 不会修改已存在文件的权限，需显式收紧
+	path := filepath.Join(s.Dir(record.ID), "cluster.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save cluster %s: %v", record.ID, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("failed to set permissions of cluster %s: %v", record.ID, err)
+	}
+	return nil
+}
+
+// FindOrCreate 按首选 Master 查找已有集群，重复部署同一集群时复用记录。
+// 新记录在返回前分配 ID，以便调用方加锁
+func (s *Store) FindOrCreate(config utils.Config) (*Record, error) {
+	record, err := s.Find(config)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		id, err := newID()
+		if err != nil {
+			return nil, err
+		}
+		return &Record{ID: id, Config: config, CreatedAt: time.Now()}, nil
+	}
+
+	record.Config = config
+	return record, nil
+}
+
+// Find 按首个 Master 的主机名与 IP 查找已有集群记录，未找到时返回 nil
+func (s *Store) Find(config utils.Config) (*Record, error) {
+	records, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	firstMasterIP := config.Masters[config.FirstMasterHostname]
+	for _, record := range records {
+		existing := record.Config
+		if existing.FirstMasterHostname == config.FirstMasterHostname &&
+			existing.Masters[existing.FirstMasterHostname] == firstMasterIP {
+			return record, nil
+		}
+	}
+	return nil, nil
+}
+
+// TryLock 标记集群正在执行操作。inventory 与 config.json 为全局共享文件，
+// 因此同一时间只允许一个集群执行操作
+func (s *Store) TryLock(id string) (func(), error) {
+	if id == "" {
+		return nil, fmt.Errorf("cannot lock a cluster without an id")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.busy != "" {
+		return nil, fmt.Errorf("cluster %s has an operation in progress", s.busy)
+	}
+	s.busy = id
+
+	return func() {
+		s.mu.Lock()
+		s.busy = ""
+		s.mu.Unlock()
+	}, nil
+}
+
+// newID 生成集群 ID
+func newID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate cluster id: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -3,9 +3,12 @@ package deploy
 import (
 	"fmt"
 	"log"
+	"os"
 	"os/exec"
-	"time"
+	"path/filepath"
 
+	"KubeCraft/internal/addon"
+	"KubeCraft/internal/etcd"
 	"KubeCraft/internal/utils"
 )
 
@@ -14,33 +17,90 @@ type ProgressReporter interface {
 	ReportProgress(message string)
 }
 
-// Playbooks 定义部署的 playbook 列表
-var Playbooks = []string{
-	"installContainerd",
-	"installNginx",
-	"installKeepalived",
-	"installKubeInit",
-	"installKubeJoin",
-	"installKubePost",
+// runtimePlaybooks 各容器运行时对应的安装 playbook
+var runtimePlaybooks = map[string]string{
+	utils.RuntimeContainerd: "installContainerd",
+	utils.RuntimeCRIO:       "installCrio",
 }
 
-// Process 执行集群部署过程
-func Process(config utils.Config, reporter ProgressReporter) error {
+// RuntimePlaybook 返回容器运行时的安装 playbook
+func RuntimePlaybook(config utils.Config) string {
+	return runtimePlaybooks[config.ContainerRuntime]
+}
+
+// loadBalancerPlaybooks 各控制面负载均衡方式在 kubeadm init 之前执行的 playbook，
+// external 与 none 不需要在主机上安装组件
+var loadBalancerPlaybooks = map[string][]string{
+	utils.LBKeepalivedNginx:   {"installNginx", "installKeepalived"},
+	utils.LBKeepalivedHAProxy: {"installHAProxy", "installKeepalived"},
+	utils.LBKubeVip:           {"installKubeVip"},
+}
+
+// LoadBalancerPlaybooks 返回控制面负载均衡的安装 playbook
+func LoadBalancerPlaybooks(config utils.Config) []string {
+	return loadBalancerPlaybooks[config.ControlPlaneLB]
+}
+
+// Playbooks 返回部署的 playbook 列表
+func Playbooks(config utils.Config) []string {
+	playbooks := []string{RuntimePlaybook(config)}
+	if config.IsExternalEtcd() {
+		playbooks = append(playbooks, "installEtcd")
+	}
+	playbooks = append(playbooks, LoadBalancerPlaybooks(config)...)
+	return append(playbooks, "installKubeInit", "installKubeJoin", "installKubePost")
+}
+
+// Process 执行集群部署过程，外部 etcd 的证书保存在 etcdPKIDir
+func Process(config utils.Config, etcdPKIDir string, reporter ProgressReporter) error {
 	log.Println("Starting cluster deployment...")
 
-	// 生成 Ansible inventory 文件到默认位置 /etc/ansible/hosts
-	reporter.ReportProgress("生成 Ansible inventory 文件到默认位置...")
-	err := config.GenerateDefaultInventory()
+	// 校验配置
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+
+	// 生成 playbook 读取的 config.json 与 inventory，调用方需持有集群锁
+	reporter.ReportProgress("生成 Ansible inventory 与配置文件...")
+	if err := config.WriteAnsibleFiles(); err != nil {
+		return err
+	}
+
+	// 生成本次部署的加入凭据，部署结束后删除并吊销
+	secrets, err := NewJoinSecrets()
+	if err != nil {
+		return err
+	}
+	varsFile, err := secrets.WriteVarsFile()
 	if err != nil {
-		return fmt.Errorf("failed to generate Ansible inventory: %v", err)
+		return err
+	}
+	defer os.Remove(varsFile)
+	defer secrets.Revoke("")
+
+	// 外部 etcd 的证书在 KubeCraft 主机上生成，由 installEtcd 分发
+	if config.IsExternalEtcd() {
+		reporter.ReportProgress("生成 etcd 证书...")
+		etcdPKIDir, err = filepath.Abs(etcdPKIDir)
+		if err != nil {
+			return err
+		}
+		if err := etcd.EnsurePKI(etcdPKIDir, config.Etcd.Hosts); err != nil {
+			return err
+		}
 	}
 
 	// 按顺序执行所有部署 playbook
-	for i, playbook := range Playbooks {
-		stepMsg := fmt.Sprintf("执行%s (%d/%d)...", playbook, i+1, len(Playbooks)+3)
+	playbooks := Playbooks(config)
+	for i, playbook := range playbooks {
+		stepMsg := fmt.Sprintf("执行%s (%d/%d)...", playbook, i+1, len(playbooks)+3)
 		reporter.ReportProgress(stepMsg)
 
-		err := executeAnsiblePlaybook(playbook)
+		args := []string{"-e", "@" + varsFile}
+		if playbook == "installEtcd" {
+			args = append(args, "-e", "etcd_pki_dir="+etcdPKIDir)
+		}
+		err := executeAnsiblePlaybook(playbook, args...)
 		if err != nil {
 			return fmt.Errorf("failed to execute playbook %s: %v", playbook, err)
 		}
@@ -49,7 +109,7 @@ func Process(config utils.Config, reporter ProgressReporter) error {
 	// 安装附加组件
 	reporter.ReportProgress("安装附加组件...")
 	log.Println("Installing additional components...")
-	err = installAdditionalComponents(reporter)
+	err = installAdditionalComponents(config, reporter)
 	if err != nil {
 		return err
 	}
@@ -58,129 +118,54 @@ func Process(config utils.Config, reporter ProgressReporter) error {
 	return nil
 }
 
-// executeAnsiblePlaybook 执行指定的 Ansible Playbook
-func executeAnsiblePlaybook(playbookName string) error {
-	// 等待一段时间确保系统准备就绪
-	time.Sleep(1 * time.Second)
-
-	// 不再需要指定 -i 参数，因为使用默认的 /etc/ansible/hosts
-	cmd := exec.Command("bash", "-c",
-		fmt.Sprintf("cd ./ansiblePlaybook && ansible-playbook %s.yaml", playbookName))
+// FetchKubeconfig 从首选 Master 拉取 admin kubeconfig 保存到 dest
+func FetchKubeconfig(dest string) error {
+	absDest, err := filepath.Abs(dest)
+	if err != nil {
+		return fmt.Errorf("failed to resolve kubeconfig path: %v", err)
+	}
 
-	// 执行命令并返回结果
-	output, err := cmd.CombinedOutput()
+	err = executeAnsiblePlaybook("fetchKubeconfig", "-e", "kubeconfig_dest="+absDest)
 	if err != nil {
-		return fmt.Errorf("command failed: %v, output: %s", err, string(output))
+		return fmt.Errorf("failed to fetch kubeconfig: %v", err)
 	}
 
-	return nil
+	return os.Chmod(absDest, 0600)
+}
+
+// executeAnsiblePlaybook 执行指定的 Ansible Playbook
+func executeAnsiblePlaybook(playbookName string, args ...string) error {
+	return utils.ExecuteAnsiblePlaybook(playbookName, args...)
 }
 
 // installAdditionalComponents 安装附加组件
-func installAdditionalComponents(reporter ProgressReporter) error {
+func installAdditionalComponents(config utils.Config, reporter ProgressReporter) error {
 	reporter.ReportProgress("安装Helm...")
 	log.Println("Installing Helm...")
 	utils.InstallHelm()
 
-	// 安装 Cilium
-	reporter.ReportProgress("安装 Cilium...")
-	log.Println("Installing Cilium...")
-	err := installCilium()
-	if err != nil {
-		return fmt.Errorf("failed to install Cilium: %v", err)
-	}
-
-	// 安装 NFS CSI
-	reporter.ReportProgress("安装 NFS CSI...")
-	log.Println("Installing NFS CSI...")
-	err = installNfsCsi()
+	// 配置 Helm Chart 仓库
+	err := addHelmRepo(config.Mirrors.HelmRepo)
 	if err != nil {
-		return fmt.Errorf("failed to install NFS CSI: %v", err)
-	}
-
-	// 安装 MetalLB
-	reporter.ReportProgress("安装 MetalLB...")
-	log.Println("Installing MetalLB...")
-	err = installMetalLB()
-	if err != nil {
-		return fmt.Errorf("failed to install MetalLB: %v", err)
-	}
-
-	// 安装 Ingress Nginx
-	reporter.ReportProgress("安装 Ingress Nginx...")
-	log.Println("Installing Ingress Nginx...")
-	err = installIngressNginx()
-	if err != nil {
-		return fmt.Errorf("failed to install Ingress Nginx: %v", err)
-	}
-
-	return nil
-}
-
-// installCilium 安装 Cilium 网络插件
-func installCilium() error {
-	// 这里应该使用 kubectl 应用 Cilium 的配置文件
-	// 由于项目中没有提供 cilium 的 yaml 文件，我们暂时留空实现
-	// 在实际应用中，这里会执行类似以下的命令：
-	// kubectl apply -f https://raw.githubusercontent.com/cilium/cilium/main/install/kubernetes/quick-install.yaml
-
-	// 示例实现:
-	// cmd := exec.Command("kubectl", "apply", "-f", "https://raw.githubusercontent.com/cilium/cilium/main/install/kubernetes/quick-install.yaml")
-	// output, err := cmd.CombinedOutput()
-	// if err != nil {
-	//     return fmt.Errorf("failed to install Cilium: %v, output: %s", err, string(output))
-	// }
-
-	log.Println("Cilium installation placeholder - to be implemented")
-	return nil
-}
-
-// installNfsCsi 安装 NFS CSI 驱动
-func installNfsCsi() error {
-	// 应用 NFS CSI 驱动相关的配置文件
-	// 根据项目结构，yaml 文件位于 ./yaml 目录下
-	csiFiles := []string{
-		"../yaml/csi-nfs-rbac.yaml",
-		"../yaml/csi-nfs-driverinfo.yaml",
-		"../yaml/csi-nfs-controller.yaml",
-		"../yaml/csi-nfs-node.yaml",
-		"../yaml/storageclass-nfs.yaml",
+		return fmt.Errorf("failed to add Helm repository: %v", err)
 	}
 
-	for _, file := range csiFiles {
-		cmd := exec.Command("kubectl", "apply", "-f", file)
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			return fmt.Errorf("failed to apply NFS CSI file %s: %v, output: %s", file, err, string(output))
-		}
-		log.Printf("Applied NFS CSI file: %s", file)
-	}
-
-	return nil
+	// 按依赖顺序安装启用的附加组件
+	return addon.InstallAll(addon.Context{Config: config}, reporter)
 }
 
-// installMetalLB 安装 MetalLB 负载均衡器
-func installMetalLB() error {
-	// 应用 MetalLB 配置文件
-	cmd := exec.Command("kubectl", "apply", "-f", "./yaml/metallb.yaml")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to apply MetalLB: %v, output: %s", err, string(output))
+// addHelmRepo 添加 Helm Chart 仓库，未配置时跳过
+func addHelmRepo(repo string) error {
+	if repo == "" {
+		return nil
 	}
 
-	log.Println("MetalLB applied successfully")
-	return nil
-}
-
-// installIngressNginx 安装 Ingress Nginx 控制器
-func installIngressNginx() error {
-	// 应用 Ingress Nginx 配置文件
-	cmd := exec.Command("kubectl", "apply", "-f", "./yaml/ingress-nginx.yaml")
+	cmd := exec.Command("helm", "repo", "add", "kubecraft", repo, "--force-update")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("failed to apply Ingress Nginx: %v, output: %s", err, string(output))
+		return fmt.Errorf("failed to add repo %s: %v, output: %s", repo, err, string(output))
 	}
 
-	log.Println("Ingress Nginx applied successfully")
+	log.Printf("Helm repository %s added", repo)
 	return nil
 }
@@ -0,0 +1,67 @@
+package deploy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"KubeCraft/internal/utils"
+)
+
+// tokenCharset bootstrap token 允许的字符
+const tokenCharset = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// JoinSecrets 节点加入集群所需的凭据，每次部署或扩容随机生成，只通过权限为 0600 的 extra-vars 文件传给 playbook
+type JoinSecrets struct {
+	Token          string `json:"bootstrap_token"` // 格式为 [a-z0-9]{6}.[a-z0-9]{16}
+	CertificateKey string `json:"certificate_key"` // 加密上传到 kubeadm-certs Secret 的控制面证书
+}
+
+// NewJoinSecrets 生成随机的 bootstrap token 与证书密钥
+func NewJoinSecrets() (JoinSecrets, error) {
+	id, err := utils.RandomString(tokenCharset, 6)
+	if err != nil {
+		return JoinSecrets{}, err
+	}
+	secret, err := utils.RandomString(tokenCharset, 16)
+	if err != nil {
+		return JoinSecrets{}, err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return JoinSecrets{}, fmt.Errorf("failed to generate certificate key: %v", err)
+	}
+
+	return JoinSecrets{Token: id + "." + secret, CertificateKey: hex.EncodeToString(key)}, nil
+}
+
+// WriteVarsFile 将凭据写入仅 root 可读的临时文件，调用方负责删除
+func (s JoinSecrets) WriteVarsFile() (string, error) {
+	file, err := os.CreateTemp("", "kubecraft-join-*.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to create join vars file: %v", err)
+	}
+	defer file.Close()
+
+	if err := json.NewEncoder(file).Encode(s); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write join vars file: %v", err)
+	}
+	return file.Name(), nil
+}
+
+// Revoke 删除 bootstrap token 与 kubeadm-certs Secret，部署或扩容完成后不再允许使用这些凭据加入集群
+func (s JoinSecrets) Revoke(kubeconfig string) {
+	id, _, _ := strings.Cut(s.Token, ".")
+	for _, secret := range []string{"bootstrap-token-" + id, "kubeadm-certs"} {
+		_, err := utils.Kubectl(kubeconfig, "-n", "kube-system", "delete", "secret", secret, "--ignore-not-found")
+		if err != nil {
+			log.Printf("Failed to revoke %s: %v", secret, err)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package etcd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"KubeCraft/internal/utils"
+)
+
+// etcdctlArgs 在 etcd 静态 Pod 中执行 etcdctl 所需的证书参数
+var etcdctlArgs = []string{
+	"etcdctl",
+	"--endpoints=https://127.0.0.1:2379",
+	"--cacert=/etc/kubernetes/pki/etcd/ca.crt",
+	"--cert=/etc/kubernetes/pki/etcd/server.crt",
+	"--key=/etc/kubernetes/pki/etcd/server.key",
+}
+
+// Member etcd 成员信息
+type Member struct {
+	ID         uint64   `json:"ID"`
+	Name       string   `json:"name"`
+	PeerURLs   []string `json:"peerURLs"`
+	ClientURLs []string `json:"clientURLs"`
+	Healthy    bool     `json:"healthy"`
+}
+
+// Client 通过 Master 上的 etcd 静态 Pod 执行 etcdctl，外部 etcd 在 KubeCraft 主机上直接执行
+type Client struct {
+	Kubeconfig string   // 集群 kubeconfig，为空时使用默认配置
+	Host       string   // 执行命令的 Master 主机名
+	Endpoints  []string // 外部 etcd 的客户端地址，非空时不经过 etcd Pod
+	PKIDir     string   // 外部 etcd 的证书目录，由 EnsurePKI 生成，包含 CA 与 API Server 客户端证书
+}
+
+// NewClient 根据集群的 etcd 拓扑创建客户端，stacked 拓扑在 host 的 etcd Pod 中执行命令，
+// 外部 etcd 使用 pkiDir 中的客户端证书访问
+func NewClient(kubeconfig string, config utils.Config, host, pkiDir string) *Client {
+	client := &Client{Kubeconfig: kubeconfig, Host: host}
+	if config.IsExternalEtcd() {
+		client.Endpoints = config.Resolved.EtcdEndpoints
+		client.PKIDir = pkiDir
+	}
+	return client
+}
+
+// external 判断是否为外部 etcd
+func (c *Client) external() bool {
+	return len(c.Endpoints) > 0
+}
+
+// exec 在 etcd Pod 中执行 etcdctl
+func (c *Client) exec(args ...string) (string, error) {
+	if c.external() {
+		if !utils.IsCommandAvailable("etcdctl") {
+			return "", fmt.Errorf("etcdctl is not installed on the KubeCraft host, it is required to manage external etcd")
+		}
+		if c.PKIDir == "" {
+			return "", fmt.Errorf("etcd PKI directory is not set for external etcd")
+		}
+		command := []string{
+			"--endpoints=" + strings.Join(c.Endpoints, ","),
+			"--cacert=" + filepath.Join(c.PKIDir, "ca.crt"),
+			"--cert=" + filepath.Join(c.PKIDir, ClientCertName+".crt"),
+			"--key=" + filepath.Join(c.PKIDir, ClientCertName+".key"),
+		}
+		output, err := exec.Command("etcdctl", append(command, args...)...).CombinedOutput()
+		if err != nil {
+			return string(output), fmt.Errorf("etcdctl %v failed: %v, output: %s", args, err, string(output))
+		}
+		return string(output), nil
+	}
+
+	command := []string{"-n", "kube-system", "exec", "etcd-" + c.Host, "--"}
+	command = append(command, etcdctlArgs...)
+	command = append(command, args...)
+	return utils.Kubectl(c.Kubeconfig, command...)
+}
+
+// Members 返回 etcd 成员列表及其健康状态
+func (c *Client) Members() ([]Member, error) {
+	output, err := c.exec("member", "list", "-w", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list etcd members: %v", err)
+	}
+
+	var list struct {
+		Members []Member `json:"members"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("failed to decode etcd member list: %v", err)
+	}
+
+	// 健康检查在部分成员不可用时返回非零状态码，仍需解析输出
+	output, _ = c.exec("endpoint", "health", "--cluster", "-w", "json")
+	var health []struct {
+		Endpoint string `json:"endpoint"`
+		Health   bool   `json:"health"`
+	}
+	if start := strings.Index(output, "["); start >= 0 {
+		_ = json.Unmarshal([]byte(output[start:]), &health)
+	}
+
+	for i, member := range list.Members {
+		for _, h := range health {
+			for _, url := range member.ClientURLs {
+				if url == h.Endpoint && h.Health {
+					list.Members[i].Healthy = true
+				}
+			}
+		}
+	}
+	return list.Members, nil
+}
+
+// RemoveMember 移除 etcd 成员
+func (c *Client) RemoveMember(id uint64) error {
+	_, err := c.exec("member", "remove", fmt.Sprintf("%x", id))
+	if err != nil {
+		return fmt.Errorf("failed to remove etcd member %x: %v", id, err)
+	}
+	return nil
+}
+
+// Quorum 返回 n 个成员的集群所需的法定人数
+func Quorum(n int) int {
+	return n/2 + 1
+}
+
+// CheckAddQuorum 添加成员前要求所有现有成员健康
+func CheckAddQuorum(members []Member) error {
+	for _, member := range members {
+		if !member.Healthy {
+			return fmt.Errorf("etcd member %s is unhealthy, fix it before adding members", member.Name)
+		}
+	}
+	return nil
+}
+
+// CheckRemoveQuorum 检查移除成员后剩余的健康成员仍满足法定人数
+func CheckRemoveQuorum(members []Member, name string) error {
+	found := false
+	healthy := 0
+	for _, member := range members {
+		if member.Name == name {
+			found = true
+			continue
+		}
+		if member.Healthy {
+			healthy++
+		}
+	}
+
+	remaining := len(members)
+	if found {
+		remaining--
+	}
+	if remaining == 0 {
+		return fmt.Errorf("cannot remove the last etcd member")
+	}
+	if healthy < Quorum(remaining) {
+		return fmt.Errorf("removing %s would leave %d healthy of %d etcd members, quorum requires %d",
+			name, healthy, remaining, Quorum(remaining))
+	}
+	return nil
+}
+
+// FindMember 按名称查找成员
+func FindMember(members []Member, name string) (Member, bool) {
+	for _, member := range members {
+		if member.Name == name {
+			return member, true
+		}
+	}
+	return Member{}, false
+}
@@ -0,0 +1,189 @@
+package etcd
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// 外部 etcd 证书有效期
+const (
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 5 * 365 * 24 * time.Hour
+)
+
+// renewBefore 证书剩余有效期不足时重新签发
+const renewBefore = 30 * 24 * time.Hour
+
+// ClientCertName API Server 访问外部 etcd 的客户端证书文件名前缀
+const ClientCertName = "apiserver-etcd-client"
+
+// EnsurePKI 在 dir 中生成外部 etcd 的 CA、各主机的 server 与 peer 证书以及 API Server 客户端证书。
+// 已存在且未临近过期的证书保持不变，新增主机时只签发新主机的证书。
+func EnsurePKI(dir string, hosts map[string]string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create etcd PKI directory: %v", err)
+	}
+
+	ca, caKey, err := loadOrCreateCA(dir)
+	if err != nil {
+		return err
+	}
+
+	for hostname, ip := range hosts {
+		names := []string{hostname, "localhost"}
+		ips := []net.IP{net.ParseIP(ip), net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+		usages := []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+
+		err := ensureCert(dir, hostname+"-server", ca, caKey, hostname, names, ips, usages)
+		if err != nil {
+			return err
+		}
+		err = ensureCert(dir, hostname+"-peer", ca, caKey, hostname, names, ips, usages)
+		if err != nil {
+			return err
+		}
+	}
+
+	return ensureCert(dir, ClientCertName, ca, caKey, "kube-apiserver-etcd-client", nil, nil,
+		[]x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth})
+}
+
+// loadOrCreateCA 读取 etcd CA，不存在时生成
+func loadOrCreateCA(dir string) (*x509.Certificate, crypto.Signer, error) {
+	cert, key, err := loadPair(dir, "ca")
+	if err == nil {
+		return cert, key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate etcd CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "etcd-ca"},
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	cert, err = sign(dir, "ca", template, caKey, nil, nil, caValidity)
+	return cert, caKey, err
+}
+
+// ensureCert 签发证书，已存在、由当前 CA 签发、SAN 一致且未临近过期时跳过，
+// 主机名或 IP 变更后重新签发
+func ensureCert(dir, name string, ca *x509.Certificate, caKey crypto.Signer, commonName string,
+	dnsNames []string, ips []net.IP, usages []x509.ExtKeyUsage) error {
+	cert, _, err := loadPair(dir, name)
+	if err == nil && cert.CheckSignatureFrom(ca) == nil && time.Until(cert.NotAfter) > renewBefore &&
+		sameSANs(cert, dnsNames, ips) {
+		return nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate key for %s: %v", name, err)
+	}
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: commonName},
+		DNSNames:    dnsNames,
+		IPAddresses: ips,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: usages,
+	}
+	_, err = sign(dir, name, template, key, ca, caKey, certValidity)
+	return err
+}
+
+// sameSANs 判断证书的 DNS 名称与 IP 地址是否与期望的完全一致，不考虑顺序
+func sameSANs(cert *x509.Certificate, dnsNames []string, ips []net.IP) bool {
+	if len(cert.DNSNames) != len(dnsNames) || len(cert.IPAddresses) != len(ips) {
+		return false
+	}
+	for _, name := range dnsNames {
+		if !slices.Contains(cert.DNSNames, name) {
+			return false
+		}
+	}
+	for _, ip := range ips {
+		if !slices.ContainsFunc(cert.IPAddresses, ip.Equal) {
+			return false
+		}
+	}
+	return true
+}
+
+// sign 使用 CA 签发证书并写入 name.crt 与 name.key，parent 为空时自签
+func sign(dir, name string, template *x509.Certificate, key *ecdsa.PrivateKey,
+	parent *x509.Certificate, parentKey crypto.Signer, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %v", err)
+	}
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(validity)
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign certificate %s: %v", name, err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode key %s: %v", name, err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key %s: %v", name, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".crt"), certPEM, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write certificate %s: %v", name, err)
+	}
+	return x509.ParseCertificate(der)
+}
+
+// loadPair 读取 name.crt 与 name.key
+func loadPair(dir, name string) (*x509.Certificate, crypto.Signer, error) {
+	certPEM, err := os.ReadFile(filepath.Join(dir, name+".crt"))
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(filepath.Join(dir, name+".key"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, nil, fmt.Errorf("invalid PEM data in %s", name)
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse certificate %s: %v", name, err)
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse key %s: %v", name, err)
+	}
+	return cert, key, nil
+}
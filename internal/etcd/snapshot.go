@@ -0,0 +1,187 @@
+package etcd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"KubeCraft/internal/utils"
+)
+
+// snapshotExt 快照文件扩展名，同名的 .sha256 文件保存校验值
+const snapshotExt = ".db"
+
+// remoteSnapshot Master 上保存快照的临时路径，位于 etcd Pod 挂载的数据目录下
+const remoteSnapshot = "/var/lib/etcd/kubecraft-snapshot.db"
+
+// Snapshot etcd 快照信息
+type Snapshot struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"createdAt"`
+	Verified  bool      `json:"verified"` // 文件内容与保存时的校验值一致
+	Error     string    `json:"error"`
+}
+
+// SaveSnapshot 生成 etcd 快照并检查完整性，保存到 dir 并写入校验值。
+// stacked 拓扑在 etcd Pod 中生成后拉取到本机，外部 etcd 直接保存到 dir。
+func (c *Client) SaveSnapshot(dir string) (Snapshot, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if err := os.MkdirAll(absDir, 0700); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to create backup directory: %v", err)
+	}
+
+	name := "snapshot-" + time.Now().Format("20060102-150405") + snapshotExt
+	dest := filepath.Join(absDir, name)
+	saved := remoteSnapshot
+	if c.external() {
+		saved = dest
+	}
+
+	if _, err := c.exec("snapshot", "save", saved); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to save etcd snapshot: %v", err)
+	}
+
+	// snapshot status 会校验快照的哈希
+	output, err := c.exec("snapshot", "status", saved, "-w", "json")
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("etcd snapshot is corrupted: %v", err)
+	}
+	var status struct {
+		TotalKey int `json:"totalKey"`
+	}
+	start := strings.Index(output, "{")
+	if start < 0 || json.Unmarshal([]byte(output[start:]), &status) != nil || status.TotalKey == 0 {
+		return Snapshot{}, fmt.Errorf("etcd snapshot is empty or unreadable: %s", output)
+	}
+
+	if !c.external() {
+		err = utils.ExecuteAnsiblePlaybook("etcdSnapshotFetch", "--limit", c.Host,
+			"-e", "snapshot_src="+remoteSnapshot, "-e", "snapshot_dest="+dest)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("failed to fetch etcd snapshot: %v", err)
+		}
+	}
+	if err := os.Chmod(dest, 0600); err != nil {
+		return Snapshot{}, err
+	}
+
+	sum, err := utils.FileSHA256(dest)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	err = os.WriteFile(dest+".sha256", []byte(fmt.Sprintf("%s  %s\n", sum, name)), 0600)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to write snapshot checksum: %v", err)
+	}
+
+	return VerifySnapshot(dir, name)
+}
+
+// VerifySnapshot 校验快照文件与保存时的 sha256 是否一致
+func VerifySnapshot(dir, name string) (Snapshot, error) {
+	if filepath.Base(name) != name || !strings.HasSuffix(name, snapshotExt) {
+		return Snapshot{}, fmt.Errorf("invalid snapshot name %q", name)
+	}
+
+	path := filepath.Join(dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("snapshot %s not found: %v", name, err)
+	}
+
+	snapshot := Snapshot{Name: name, Size: info.Size(), CreatedAt: info.ModTime()}
+	snapshot.SHA256, err = utils.FileSHA256(path)
+	if err != nil {
+		return snapshot, err
+	}
+
+	data, err := os.ReadFile(path + ".sha256")
+	fields := strings.Fields(string(data))
+	switch {
+	case err != nil || len(fields) == 0:
+		snapshot.Error = "checksum file missing"
+	case fields[0] != snapshot.SHA256:
+		snapshot.Error = "checksum mismatch"
+	default:
+		snapshot.Verified = true
+	}
+	return snapshot, nil
+}
+
+// ListSnapshots 返回 dir 下的快照，按时间从新到旧排序
+func ListSnapshots(dir string) ([]Snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %v", err)
+	}
+
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), snapshotExt) {
+			continue
+		}
+		snapshot, err := VerifySnapshot(dir, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// PruneSnapshots 只保留最新的 keep 个快照
+func PruneSnapshots(dir string, keep int) error {
+	snapshots, err := ListSnapshots(dir)
+	if err != nil {
+		return err
+	}
+	if keep <= 0 || len(snapshots) <= keep {
+		return nil
+	}
+
+	for _, snapshot := range snapshots[keep:] {
+		path := filepath.Join(dir, snapshot.Name)
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove snapshot %s: %v", snapshot.Name, err)
+		}
+		if err := os.Remove(path + ".sha256"); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove snapshot checksum %s: %v", snapshot.Name, err)
+		}
+	}
+	return nil
+}
+
+// RestoreSnapshot 在所有 Master 上停止 etcd 与 API Server，从快照恢复数据目录后重新启动
+func RestoreSnapshot(dir, name string) error {
+	snapshot, err := VerifySnapshot(dir, name)
+	if err != nil {
+		return err
+	}
+	if !snapshot.Verified {
+		return fmt.Errorf("snapshot %s failed verification: %s", name, snapshot.Error)
+	}
+
+	path, err := filepath.Abs(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	if err := utils.ExecuteAnsiblePlaybook("etcdRestore", "-e", "snapshot_path="+path); err != nil {
+		return fmt.Errorf("failed to restore etcd snapshot: %v", err)
+	}
+	return nil
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"log"
 	"os/exec"
-	"time"
 )
 
 // ProgressReporter 进度报告接口
@@ -33,6 +32,11 @@ var Playbooks = []string{
 func Process(config utils.Config, reporter ProgressReporter) error {
 	log.Println("Starting cluster initialization...")
 
+	// 校验配置
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+
 	reporter.ReportProgress("检查Ansible安装状态...")
 	// 安装 ansible（如果尚未安装）
 	err := installAnsible(reporter)
@@ -40,11 +44,10 @@ func Process(config utils.Config, reporter ProgressReporter) error {
 		return fmt.Errorf("failed to install Ansible: %v", err)
 	}
 
-	// 生成 Ansible inventory 文件到默认位置 /etc/ansible/hosts
-	reporter.ReportProgress("生成 Ansible inventory 文件到默认位置...")
-	err = config.GenerateDefaultInventory()
-	if err != nil {
-		return fmt.Errorf("failed to generate Ansible inventory: %v", err)
+	// 生成 playbook 读取的 config.json 与 inventory，调用方需持有集群锁
+	reporter.ReportProgress("生成 Ansible inventory 与配置文件...")
+	if err := config.WriteAnsibleFiles(); err != nil {
+		return err
 	}
 
 	// 按顺序执行所有初始化 playbook
@@ -101,21 +104,8 @@ func installAnsible(reporter ProgressReporter) error {
 }
 
 // executeAnsiblePlaybook 执行指定的 Ansible Playbook
-func executeAnsiblePlaybook(playbookName string) error {
-	// 等待一段时间确保系统准备就绪
-	time.Sleep(1 * time.Second)
-
-	// 不再需要指定 -i 参数，因为使用默认的 /etc/ansible/hosts
-	cmd := exec.Command("bash", "-c",
-		fmt.Sprintf("cd ./ansiblePlaybook && ansible-playbook %s.yaml", playbookName))
-
-	// 执行命令并返回结果
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("command failed: %v, output: %s", err, string(output))
-	}
-
-	return nil
+func executeAnsiblePlaybook(playbookName string, args ...string) error {
+	return utils.ExecuteAnsiblePlaybook(playbookName, args...)
 }
 
 // isCommandAvailable 检查命令是否可用
@@ -0,0 +1,299 @@
+package kubeconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"KubeCraft/internal/cluster"
+	"KubeCraft/internal/utils"
+)
+
+// 用户证书有效期
+const (
+	DefaultExpirationHours = 24 * 30
+	MaxExpirationHours     = 24 * 365
+)
+
+// bindingPrefix KubeCraft 为用户创建的角色绑定名称前缀，完整名称为 <前缀><用户名>:<角色类型>:<角色名>
+const bindingPrefix = "kubecraft:user:"
+
+// namePattern 用户名与角色名需符合 Kubernetes 对象名格式
+var namePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
+// UserRequest 签发用户 kubeconfig 的请求
+type UserRequest struct {
+	Username        string   `json:"username"`        // 证书 CN，即 Kubernetes 用户名
+	Groups          []string `json:"groups"`          // 证书 O，即用户组
+	ClusterRole     string   `json:"clusterRole"`     // 绑定的 ClusterRole，指定 Namespace 时只在该命名空间内生效
+	Role            string   `json:"role"`            // 绑定的命名空间 Role，需同时指定 Namespace
+	Namespace       string   `json:"namespace"`       // 授权的命名空间，同时作为 kubeconfig 的默认命名空间
+	ExpirationHours int      `json:"expirationHours"` // 证书有效期，默认 30 天，最长 1 年
+}
+
+// Validate 校验请求参数并设置默认有效期
+func (req *UserRequest) Validate() error {
+	if !namePattern.MatchString(req.Username) {
+		return fmt.Errorf("invalid username %q", req.Username)
+	}
+	if strings.HasPrefix(req.Username, "system:") {
+		return fmt.Errorf("username must not use the reserved system: prefix")
+	}
+	for _, group := range req.Groups {
+		if strings.HasPrefix(group, "system:") {
+			return fmt.Errorf("group %q uses the reserved system: prefix", group)
+		}
+	}
+
+	switch {
+	case req.ClusterRole == "" && req.Role == "":
+		return fmt.Errorf("either clusterRole or role is required")
+	case req.ClusterRole != "" && req.Role != "":
+		return fmt.Errorf("clusterRole and role are mutually exclusive")
+	case req.Role != "" && req.Namespace == "":
+		return fmt.Errorf("namespace is required when binding a role")
+	}
+
+	if req.ExpirationHours == 0 {
+		req.ExpirationHours = DefaultExpirationHours
+	}
+	if req.ExpirationHours < 1 || req.ExpirationHours > MaxExpirationHours {
+		return fmt.Errorf("expirationHours must be between 1 and %d", MaxExpirationHours)
+	}
+	return nil
+}
+
+// Admin 返回指向控制面入口的 admin kubeconfig
+func Admin(store *cluster.Store, record *cluster.Record) ([]byte, error) {
+	source := store.Kubeconfig(record.ID)
+	if source == "" {
+		return nil, fmt.Errorf("cluster %s has no saved kubeconfig", record.ID)
+	}
+
+	ca, clientCert, clientKey, err := readCredentials(source)
+	if err != nil {
+		return nil, err
+	}
+	return render(record.Config.ControlPlaneURL(), ca, "kubernetes-admin", clientCert, clientKey, ""), nil
+}
+
+// IssueUser 通过 CertificateSigningRequest 签发用户证书，创建角色绑定并返回用户 kubeconfig
+func IssueUser(store *cluster.Store, record *cluster.Record, req UserRequest) ([]byte, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	admin := store.Kubeconfig(record.ID)
+	if admin == "" {
+		return nil, fmt.Errorf("cluster %s has no saved kubeconfig", record.ID)
+	}
+	ca, _, _, err := readCredentials(admin)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate private key: %v", err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: req.Username, Organization: req.Groups},
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate request: %v", err)
+	}
+
+	// 先创建角色绑定，绑定失败时不签发证书
+	log.Printf("Issuing kubeconfig for user %s in cluster %s", req.Username, record.ID)
+	if err := bind(admin, req); err != nil {
+		return nil, err
+	}
+	cert, err := signCertificate(admin, req, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}))
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return render(record.Config.ControlPlaneURL(), ca, req.Username, cert, keyPEM, req.Namespace), nil
+}
+
+// signCertificate 提交 CSR 并批准，返回签发的证书，完成后删除 CSR 对象
+func signCertificate(kubeconfig string, req UserRequest, csr []byte) ([]byte, error) {
+	name := fmt.Sprintf("kubecraft-%s-%d", req.Username, time.Now().Unix())
+	manifest := map[string]any{
+		"apiVersion": "certificates.k8s.io/v1",
+		"kind":       "CertificateSigningRequest",
+		"metadata":   map[string]any{"name": name},
+		"spec": map[string]any{
+			"request":           base64.StdEncoding.EncodeToString(csr),
+			"signerName":        "kubernetes.io/kube-apiserver-client",
+			"expirationSeconds": req.ExpirationHours * 3600,
+			"usages":            []string{"client auth"},
+		},
+	}
+	if err := apply(kubeconfig, manifest); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if _, err := utils.Kubectl(kubeconfig, "delete", "csr", name, "--ignore-not-found"); err != nil {
+			log.Printf("Failed to delete CSR %s: %v", name, err)
+		}
+	}()
+
+	if _, err := utils.Kubectl(kubeconfig, "certificate", "approve", name); err != nil {
+		return nil, fmt.Errorf("failed to approve CSR %s: %v", name, err)
+	}
+
+	// 批准后由 kube-controller-manager 异步签发
+	for i := 0; i < 30; i++ {
+		output, err := utils.Kubectl(kubeconfig, "get", "csr", name, "-o", "jsonpath={.status.certificate}")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get CSR %s: %v", name, err)
+		}
+		if output != "" {
+			cert, err := base64.StdEncoding.DecodeString(output)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode issued certificate: %v", err)
+			}
+			return cert, nil
+		}
+		time.Sleep(time.Second)
+	}
+	return nil, fmt.Errorf("CSR %s was approved but no certificate was issued", name)
+}
+
+// bind 为用户创建 ClusterRoleBinding 或命名空间内的 RoleBinding，并删除该用户之前签发时创建的其他绑定。
+// roleRef 创建后不可修改，绑定名称包含角色，更换角色时创建新的绑定。
+func bind(kubeconfig string, req UserRequest) error {
+	roleKind, roleName := "ClusterRole", req.ClusterRole
+	if req.Role != "" {
+		roleKind, roleName = "Role", req.Role
+	}
+
+	prefix := bindingPrefix + req.Username
+	name := fmt.Sprintf("%s:%s:%s", prefix, strings.ToLower(roleKind), roleName)
+	metadata := map[string]any{"name": name}
+	kind := "ClusterRoleBinding"
+	if req.Namespace != "" {
+		kind = "RoleBinding"
+		metadata["namespace"] = req.Namespace
+	}
+
+	err := apply(kubeconfig, map[string]any{
+		"apiVersion": "rbac.authorization.k8s.io/v1",
+		"kind":       kind,
+		"metadata":   metadata,
+		"roleRef":    map[string]any{"apiGroup": "rbac.authorization.k8s.io", "kind": roleKind, "name": roleName},
+		"subjects": []any{
+			map[string]any{"apiGroup": "rbac.authorization.k8s.io", "kind": "User", "name": req.Username},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// 删除更换角色或命名空间之前的绑定
+	output, err := utils.Kubectl(kubeconfig, "get", "clusterrolebindings,rolebindings", "-A", "-o",
+		`jsonpath={range .items[*]}{.kind}{" "}{.metadata.name}{" "}{.metadata.namespace}{"\n"}{end}`)
+	if err != nil {
+		return fmt.Errorf("failed to list role bindings: %v", err)
+	}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		existingKind, existingName := fields[0], fields[1]
+		existingNamespace := ""
+		if len(fields) > 2 {
+			existingNamespace = fields[2]
+		}
+		if existingName != prefix && !strings.HasPrefix(existingName, prefix+":") {
+			continue
+		}
+		if existingKind == kind && existingName == name && existingNamespace == req.Namespace {
+			continue
+		}
+
+		args := []string{"delete", strings.ToLower(existingKind), existingName, "--ignore-not-found"}
+		if existingNamespace != "" {
+			args = append(args, "-n", existingNamespace)
+		}
+		if _, err := utils.Kubectl(kubeconfig, args...); err != nil {
+			return fmt.Errorf("failed to delete stale %s %s: %v", existingKind, existingName, err)
+		}
+		log.Printf("Deleted stale %s %s of user %s", existingKind, existingName, req.Username)
+	}
+	return nil
+}
+
+// apply 执行 kubectl apply
+func apply(kubeconfig string, manifest map[string]any) error {
+	if err := utils.KubectlApply(kubeconfig, manifest); err != nil {
+		return fmt.Errorf("failed to apply %s: %v", manifest["kind"], err)
+	}
+	return nil
+}
+
+// readCredentials 读取 kubeconfig 中的 CA 与客户端证书
+func readCredentials(kubeconfig string) (ca, cert, key []byte, err error) {
+	fields := []string{
+		"{.clusters[0].cluster.certificate-authority-data}",
+		"{.users[0].user.client-certificate-data}",
+		"{.users[0].user.client-key-data}",
+	}
+
+	values := make([][]byte, len(fields))
+	for i, field := range fields {
+		output, err := utils.Kubectl(kubeconfig, "config", "view", "--raw", "--minify", "-o", "jsonpath="+field)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to read kubeconfig: %v", err)
+		}
+		values[i], err = base64.StdEncoding.DecodeString(strings.TrimSpace(output))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to decode kubeconfig field %s: %v", field, err)
+		}
+	}
+	return values[0], values[1], values[2], nil
+}
+
+// render 生成 kubeconfig
+func render(server string, ca []byte, user string, cert, key []byte, namespace string) []byte {
+	var builder strings.Builder
+	builder.WriteString("apiVersion: v1\n")
+	builder.WriteString("kind: Config\n")
+	builder.WriteString("clusters:\n")
+	builder.WriteString("- name: kubernetes\n")
+	builder.WriteString("  cluster:\n")
+	builder.WriteString(fmt.Sprintf("    server: %s\n", server))
+	builder.WriteString(fmt.Sprintf("    certificate-authority-data: %s\n", base64.StdEncoding.EncodeToString(ca)))
+	builder.WriteString("users:\n")
+	builder.WriteString(fmt.Sprintf("- name: %s\n", user))
+	builder.WriteString("  user:\n")
+	builder.WriteString(fmt.Sprintf("    client-certificate-data: %s\n", base64.StdEncoding.EncodeToString(cert)))
+	builder.WriteString(fmt.Sprintf("    client-key-data: %s\n", base64.StdEncoding.EncodeToString(key)))
+	builder.WriteString("contexts:\n")
+	builder.WriteString(fmt.Sprintf("- name: %s@kubernetes\n", user))
+	builder.WriteString("  context:\n")
+	builder.WriteString("    cluster: kubernetes\n")
+	builder.WriteString(fmt.Sprintf("    user: %s\n", user))
+	if namespace != "" {
+		builder.WriteString(fmt.Sprintf("    namespace: %s\n", namespace))
+	}
+	builder.WriteString(fmt.Sprintf("current-context: %s@kubernetes\n", user))
+	return []byte(builder.String())
+}
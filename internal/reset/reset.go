@@ -0,0 +1,127 @@
+package reset
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"KubeCraft/internal/cluster"
+	"KubeCraft/internal/scale"
+	"KubeCraft/internal/utils"
+)
+
+// ProgressReporter 进度报告接口
+type ProgressReporter interface {
+	ReportProgress(message string)
+}
+
+// Request 重置请求
+type Request struct {
+	Hosts         []string `json:"hosts"`         // 要重置的主机名，为空时重置整个集群
+	RemoveRuntime bool     `json:"removeRuntime"` // 同时卸载容器运行时
+}
+
+// Steps 返回重置的进度步数，部分重置时包含移除各主机的步骤
+func Steps(config utils.Config, req Request) int {
+	steps := 3
+	if len(req.Hosts) > 0 {
+		steps++
+	}
+	for _, host := range req.Hosts {
+		if _, ok := config.Masters[host]; ok {
+			steps += 9
+		} else {
+			steps += 8
+		}
+	}
+	return steps
+}
+
+// Reset 撤销主机上的部署：kubeadm reset、清理 CNI 与 iptables/IPVS、卸载控制面负载均衡组件，
+// 可选卸载容器运行时，并清理部署时的临时文件。重置后可在这些主机上重新部署。
+// 部分重置时先按移除节点的流程将主机移出集群，Master 需通过 etcd 法定人数检查。
+func Reset(store *cluster.Store, record *cluster.Record, req Request, reporter ProgressReporter) error {
+	config := record.Config
+	masters := 0
+	for _, host := range req.Hosts {
+		_, isMaster := config.Masters[host]
+		_, isNode := config.Nodes[host]
+		if !isMaster && !isNode {
+			return fmt.Errorf("host %s not found in cluster", host)
+		}
+		if isMaster {
+			masters++
+		}
+	}
+	if masters > 0 && masters == len(config.Masters) {
+		return fmt.Errorf("cannot reset all masters, reset the whole cluster instead")
+	}
+	whole := len(req.Hosts) == 0
+
+	if !whole {
+		if err := removeHosts(store, record, req.Hosts, reporter); err != nil {
+			return err
+		}
+	}
+
+	// 使用移除前的配置生成 inventory，被移除的主机仍需执行重置
+	reporter.ReportProgress("生成 Ansible inventory 与配置文件...")
+	if err := config.WriteAnsibleFiles(); err != nil {
+		return err
+	}
+
+	args := []string{"-e", fmt.Sprintf("remove_runtime=%t", req.RemoveRuntime)}
+	target := "所有主机"
+	if !whole {
+		target = strings.Join(req.Hosts, ",")
+		args = append(args, "--limit", target)
+	}
+
+	log.Printf("Resetting cluster %s hosts: %s", record.ID, target)
+	reporter.ReportProgress(fmt.Sprintf("重置%s...", target))
+	if err := utils.ExecuteAnsiblePlaybook("resetHost", args...); err != nil {
+		return fmt.Errorf("failed to reset hosts: %v", err)
+	}
+
+	if !whole {
+		reporter.ReportProgress("更新 Ansible inventory 与配置文件...")
+		if err := record.Config.WriteAnsibleFiles(); err != nil {
+			return err
+		}
+	}
+
+	// 整个集群重置后，保存的 kubeconfig 已失效
+	if whole {
+		reporter.ReportProgress("更新集群记录...")
+		if err := os.Remove(store.KubeconfigPath(record.ID)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove kubeconfig: %v", err)
+		}
+		record.Status = cluster.StatusReset
+		if err := store.Save(record); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Reset of cluster %s finished", record.ID)
+	return nil
+}
+
+// removeHosts 将主机移出集群并更新集群记录。Master 先检查 etcd 法定人数并移除 etcd 成员，
+// worker 节点驱逐失败时仍继续移除。
+func removeHosts(store *cluster.Store, record *cluster.Record, hosts []string, reporter ProgressReporter) error {
+	for _, host := range hosts {
+		if _, ok := record.Config.Masters[host]; ok {
+			if err := scale.RemoveMaster(store, record, host, reporter); err != nil {
+				return err
+			}
+			continue
+		}
+
+		options := scale.RemoveOptions{GracePeriod: -1, Force: true}
+		if err := scale.RemoveNode(store, record, host, options, reporter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package scale
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"KubeCraft/internal/cluster"
+	"KubeCraft/internal/deploy"
+	"KubeCraft/internal/etcd"
+	"KubeCraft/internal/initialize"
+	"KubeCraft/internal/utils"
+)
+
+// AddMasters 向已有集群添加控制面节点：检查 etcd 健康状态、加入集群并更新所有 Master 的负载均衡配置
+func AddMasters(store *cluster.Store, record *cluster.Record, masters, secondaryIPs map[string]string, reporter ProgressReporter) error {
+	log.Printf("Adding masters to cluster %s: %v", record.ID, masters)
+
+	config := record.Config
+	if err := checkNewHosts(config, masters); err != nil {
+		return err
+	}
+
+	// stacked 拓扑中每个 Master 都是 etcd 成员，成员数为偶数时容错能力与少一个成员相同，不允许
+	kubeconfig := store.Kubeconfig(record.ID)
+	if !config.IsExternalEtcd() {
+		total := len(config.Masters) + len(masters)
+		if total%2 == 0 {
+			return fmt.Errorf("cluster would have %d masters, the number of etcd members must be odd", total)
+		}
+
+		reporter.ReportProgress("检查 etcd 集群健康状态...")
+		client := &etcd.Client{Kubeconfig: kubeconfig, Host: config.FirstMasterHostname}
+		members, err := client.Members()
+		if err != nil {
+			return err
+		}
+		if err := etcd.CheckAddQuorum(members); err != nil {
+			return err
+		}
+	}
+
+	// 合并新 Master 到集群配置
+	config.Masters = maps.Clone(config.Masters)
+	maps.Copy(config.Masters, masters)
+	mergeSecondaryIPs(&config, secondaryIPs)
+	config.ApplyDefaults()
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+
+	reporter.ReportProgress("生成 Ansible inventory 与配置文件...")
+	if err := config.WriteAnsibleFiles(); err != nil {
+		return err
+	}
+
+	hostnames := slices.Sorted(maps.Keys(masters))
+	limit := strings.Join(hostnames, ",")
+
+	// 初始化新主机，/etc/hosts 需要在所有主机上更新
+	for _, playbook := range initialize.Playbooks {
+		args := []string{"--limit", limit}
+		if playbook == "initUpdateEtcHosts" {
+			args = nil
+		}
+
+		reporter.ReportProgress(fmt.Sprintf("执行%s...", playbook))
+		if err := utils.ExecuteAnsiblePlaybook(playbook, args...); err != nil {
+			return fmt.Errorf("failed to execute playbook %s: %v", playbook, err)
+		}
+	}
+
+	// 生成本次扩容的加入凭据，扩容结束后删除并吊销
+	secrets, err := deploy.NewJoinSecrets()
+	if err != nil {
+		return err
+	}
+	varsFile, err := secrets.WriteVarsFile()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(varsFile)
+	defer secrets.Revoke(kubeconfig)
+
+	// 安装容器运行时与负载均衡，以控制面身份加入集群
+	playbooks := []string{deploy.RuntimePlaybook(config)}
+	playbooks = append(playbooks, deploy.LoadBalancerPlaybooks(config)...)
+	playbooks = append(playbooks, "scaleAddMaster", "installKubePost")
+	for _, playbook := range playbooks {
+		reporter.ReportProgress(fmt.Sprintf("执行%s...", playbook))
+		if err := utils.ExecuteAnsiblePlaybook(playbook, "--limit", limit, "-e", "@"+varsFile); err != nil {
+			return fmt.Errorf("failed to execute playbook %s: %v", playbook, err)
+		}
+	}
+
+	// 所有 Master 的代理 upstream 与 keepalived 配置需要包含新成员
+	reporter.ReportProgress("执行reconfigureLoadBalancer...")
+	if err := utils.ExecuteAnsiblePlaybook("reconfigureLoadBalancer"); err != nil {
+		return fmt.Errorf("failed to execute playbook reconfigureLoadBalancer: %v", err)
+	}
+	if config.ControlPlaneLB == utils.LBExternal {
+		log.Printf("Masters %s must be added to the external load balancer %s", limit, config.ExternalLBAddress)
+		reporter.ReportProgress(fmt.Sprintf("请将 %s 加入外部负载均衡 %s 的后端", limit, config.ExternalLBAddress))
+	}
+
+	for _, hostname := range hostnames {
+		reporter.ReportProgress(fmt.Sprintf("等待节点 %s 就绪...", hostname))
+		_, err := utils.Kubectl(kubeconfig, "wait", "--for=condition=Ready", "node/"+hostname, "--timeout=5m")
+		if err != nil {
+			return fmt.Errorf("node %s is not ready: %v", hostname, err)
+		}
+	}
+
+	record.Config = config
+	if err := store.Save(record); err != nil {
+		return err
+	}
+
+	log.Printf("Masters added to cluster %s", record.ID)
+	return nil
+}
+
+// RemoveMaster 从集群中移除控制面节点：确认移除后 etcd 仍满足法定人数，移除 etcd 成员与节点，并更新负载均衡配置
+func RemoveMaster(store *cluster.Store, record *cluster.Record, hostname string, reporter ProgressReporter) error {
+	log.Printf("Removing master %s from cluster %s", hostname, record.ID)
+
+	config := record.Config
+	if _, ok := config.Masters[hostname]; !ok {
+		return fmt.Errorf("master %s not found in cluster", hostname)
+	}
+	if len(config.Masters) == 1 {
+		return fmt.Errorf("cannot remove the last master")
+	}
+
+	// 外部 etcd 的成员不随 Master 变化，首个 Master 被移除时由其余 Master 中主机名最小的接替
+	kubeconfig := store.Kubeconfig(record.ID)
+	var client *etcd.Client
+	var members []etcd.Member
+	remaining := slices.DeleteFunc(slices.Sorted(maps.Keys(config.Masters)), func(host string) bool { return host == hostname })
+	successor := remaining[0]
+	if !config.IsExternalEtcd() {
+		reporter.ReportProgress("检查 etcd 集群健康状态...")
+		var err error
+		client, members, err = remainingMemberClient(kubeconfig, config, hostname)
+		if err != nil {
+			return err
+		}
+		if err := etcd.CheckRemoveQuorum(members, hostname); err != nil {
+			return err
+		}
+		successor = client.Host
+	}
+
+	// 待移除的主机可能已不可用，驱逐失败不影响后续步骤
+	reporter.ReportProgress(fmt.Sprintf("驱逐节点 %s 上的 Pod...", hostname))
+	_, err := utils.Kubectl(kubeconfig, "drain", hostname, "--ignore-daemonsets", "--delete-emptydir-data", "--timeout=2m")
+	if err != nil {
+		log.Printf("Failed to drain node %s: %v", hostname, err)
+	}
+
+	if client != nil {
+		reporter.ReportProgress(fmt.Sprintf("移除 etcd 成员 %s...", hostname))
+		if member, ok := etcd.FindMember(members, hostname); ok {
+			if err := client.RemoveMember(member.ID); err != nil {
+				return err
+			}
+		} else {
+			log.Printf("etcd member %s not found, skipping", hostname)
+		}
+	}
+
+	reporter.ReportProgress(fmt.Sprintf("重置主机 %s...", hostname))
+	if err := config.WriteAnsibleFiles(); err != nil {
+		return err
+	}
+	if err := utils.ExecuteAnsiblePlaybook("resetKubeadm", "--limit", hostname); err != nil {
+		log.Printf("Failed to reset host %s: %v", hostname, err)
+	}
+
+	reporter.ReportProgress(fmt.Sprintf("删除节点 %s...", hostname))
+	_, err = utils.Kubectl(kubeconfig, "delete", "node", hostname, "--ignore-not-found")
+	if err != nil {
+		return fmt.Errorf("failed to delete node %s: %v", hostname, err)
+	}
+
+	// 从集群配置中移除，首个 Master 被移除时由执行 etcdctl 的 Master 接替
+	config.Masters = maps.Clone(config.Masters)
+	delete(config.Masters, hostname)
+	removeSecondaryIP(&config, hostname)
+	if config.FirstMasterHostname == hostname {
+		config.FirstMasterHostname = successor
+	}
+	config.ApplyDefaults()
+
+	reporter.ReportProgress("生成 Ansible inventory 与配置文件...")
+	if err := config.WriteAnsibleFiles(); err != nil {
+		return err
+	}
+
+	for _, playbook := range []string{"initUpdateEtcHosts", "reconfigureLoadBalancer"} {
+		reporter.ReportProgress(fmt.Sprintf("执行%s...", playbook))
+		if err := utils.ExecuteAnsiblePlaybook(playbook); err != nil {
+			return fmt.Errorf("failed to execute playbook %s: %v", playbook, err)
+		}
+	}
+	if config.ControlPlaneLB == utils.LBExternal {
+		log.Printf("Master %s must be removed from the external load balancer %s", hostname, config.ExternalLBAddress)
+		reporter.ReportProgress(fmt.Sprintf("请将 %s 从外部负载均衡 %s 的后端移除", hostname, config.ExternalLBAddress))
+	}
+
+	record.Config = config
+	if err := store.Save(record); err != nil {
+		return err
+	}
+
+	log.Printf("Master %s removed from cluster %s", hostname, record.ID)
+	return nil
+}
+
+// remainingMemberClient 在除 exclude 以外的 Master 中找到可执行 etcdctl 的主机，优先使用首个 Master
+func remainingMemberClient(kubeconfig string, config utils.Config, exclude string) (*etcd.Client, []etcd.Member, error) {
+	hosts := slices.Sorted(maps.Keys(config.Masters))
+	if i := slices.Index(hosts, config.FirstMasterHostname); i > 0 {
+		hosts = slices.Insert(slices.Delete(hosts, i, i+1), 0, config.FirstMasterHostname)
+	}
+
+	var lastErr error
+	for _, host := range hosts {
+		if host == exclude {
+			continue
+		}
+
+		client := &etcd.Client{Kubeconfig: kubeconfig, Host: host}
+		members, err := client.Members()
+		if err != nil {
+			log.Printf("Failed to query etcd on %s: %v", host, err)
+			lastErr = err
+			continue
+		}
+		return client, members, nil
+	}
+	return nil, nil, fmt.Errorf("no reachable etcd member: %v", lastErr)
+}
@@ -0,0 +1,241 @@
+package scale
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"KubeCraft/internal/cluster"
+	"KubeCraft/internal/deploy"
+	"KubeCraft/internal/initialize"
+	"KubeCraft/internal/utils"
+)
+
+// ProgressReporter 进度报告接口
+type ProgressReporter interface {
+	ReportProgress(message string)
+}
+
+// AddNodes 向已有集群添加 worker 节点：初始化主机、加入集群、打标签并更新集群记录
+func AddNodes(store *cluster.Store, record *cluster.Record, nodes, secondaryIPs map[string]string, reporter ProgressReporter) error {
+	log.Printf("Adding nodes to cluster %s: %v", record.ID, nodes)
+
+	config := record.Config
+	if err := checkNewHosts(config, nodes); err != nil {
+		return err
+	}
+
+	// 合并新节点到集群配置
+	config.Nodes = maps.Clone(config.Nodes)
+	if config.Nodes == nil {
+		config.Nodes = make(map[string]string)
+	}
+	maps.Copy(config.Nodes, nodes)
+	mergeSecondaryIPs(&config, secondaryIPs)
+	config.ApplyDefaults()
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+
+	reporter.ReportProgress("生成 Ansible inventory 与配置文件...")
+	if err := config.WriteAnsibleFiles(); err != nil {
+		return err
+	}
+
+	hostnames := slices.Sorted(maps.Keys(nodes))
+	limit := strings.Join(hostnames, ",")
+
+	// 初始化新主机，/etc/hosts 需要在所有主机上更新
+	for _, playbook := range initialize.Playbooks {
+		args := []string{"--limit", limit}
+		if playbook == "initUpdateEtcHosts" {
+			args = nil
+		}
+
+		reporter.ReportProgress(fmt.Sprintf("执行%s...", playbook))
+		if err := utils.ExecuteAnsiblePlaybook(playbook, args...); err != nil {
+			return fmt.Errorf("failed to execute playbook %s: %v", playbook, err)
+		}
+	}
+
+	// 生成本次扩容的加入凭据，扩容结束后删除并吊销
+	kubeconfig := store.Kubeconfig(record.ID)
+	secrets, err := deploy.NewJoinSecrets()
+	if err != nil {
+		return err
+	}
+	varsFile, err := secrets.WriteVarsFile()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(varsFile)
+	defer secrets.Revoke(kubeconfig)
+
+	// 安装容器运行时、加入集群并执行安装后配置
+	for _, playbook := range []string{deploy.RuntimePlaybook(config), "scaleAddNode", "installKubePost"} {
+		reporter.ReportProgress(fmt.Sprintf("执行%s...", playbook))
+		if err := utils.ExecuteAnsiblePlaybook(playbook, "--limit", limit, "-e", "@"+varsFile); err != nil {
+			return fmt.Errorf("failed to execute playbook %s: %v", playbook, err)
+		}
+	}
+
+	// 等待新节点就绪
+	for _, hostname := range hostnames {
+		reporter.ReportProgress(fmt.Sprintf("等待节点 %s 就绪...", hostname))
+		_, err := utils.Kubectl(kubeconfig, "wait", "--for=condition=Ready", "node/"+hostname, "--timeout=5m")
+		if err != nil {
+			return fmt.Errorf("node %s is not ready: %v", hostname, err)
+		}
+	}
+
+	// 更新集群记录
+	record.Config = config
+	if err := store.Save(record); err != nil {
+		return err
+	}
+
+	log.Printf("Nodes added to cluster %s", record.ID)
+	return nil
+}
+
+// mergeSecondaryIPs 合并新主机在双栈集群中的第二地址
+func mergeSecondaryIPs(config *utils.Config, secondaryIPs map[string]string) {
+	if len(secondaryIPs) == 0 {
+		return
+	}
+	config.SecondaryIPs = maps.Clone(config.SecondaryIPs)
+	if config.SecondaryIPs == nil {
+		config.SecondaryIPs = make(map[string]string)
+	}
+	maps.Copy(config.SecondaryIPs, secondaryIPs)
+}
+
+// removeSecondaryIP 移除主机的第二地址
+func removeSecondaryIP(config *utils.Config, hostname string) {
+	if _, ok := config.SecondaryIPs[hostname]; ok {
+		config.SecondaryIPs = maps.Clone(config.SecondaryIPs)
+		delete(config.SecondaryIPs, hostname)
+	}
+}
+
+// checkNewHosts 检查新主机的主机名和 IP 未被集群使用
+func checkNewHosts(config utils.Config, hosts map[string]string) error {
+	if len(hosts) == 0 {
+		return fmt.Errorf("no hosts specified")
+	}
+
+	used := make(map[string]string)
+	for hostname, ip := range config.Masters {
+		used[hostname] = ip
+	}
+	for hostname, ip := range config.Nodes {
+		used[hostname] = ip
+	}
+	usedIPs := make(map[string]string)
+	for hostname, ip := range used {
+		usedIPs[ip] = hostname
+	}
+
+	for hostname, ip := range hosts {
+		if hostname == "" || ip == "" {
+			return fmt.Errorf("hostname and ip are required")
+		}
+		if _, ok := used[hostname]; ok {
+			return fmt.Errorf("host %s already exists in cluster", hostname)
+		}
+		if existing, ok := usedIPs[ip]; ok {
+			return fmt.Errorf("ip %s is already used by host %s", ip, existing)
+		}
+	}
+	return nil
+}
+
+// RemoveOptions 移除 worker 节点的驱逐选项
+type RemoveOptions struct {
+	GracePeriod     int  `json:"gracePeriod"`     // Pod 终止宽限期（秒），-1 使用 Pod 自身的设置
+	Timeout         int  `json:"timeout"`         // 驱逐超时（秒），默认 300
+	DisableEviction bool `json:"disableEviction"` // 直接删除 Pod 而不使用 Eviction API，会绕过 PodDisruptionBudget
+	Force           bool `json:"force"`           // 驱逐失败时仍继续移除节点
+}
+
+// RemoveNode 从集群中移除 worker 节点：驱逐 Pod、删除 Node 对象、重置主机并更新集群记录
+func RemoveNode(store *cluster.Store, record *cluster.Record, hostname string, options RemoveOptions, reporter ProgressReporter) error {
+	log.Printf("Removing node %s from cluster %s", hostname, record.ID)
+
+	config := record.Config
+	if _, ok := config.Nodes[hostname]; !ok {
+		return fmt.Errorf("node %s not found in cluster", hostname)
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 300
+	}
+
+	kubeconfig := store.Kubeconfig(record.ID)
+
+	reporter.ReportProgress(fmt.Sprintf("禁止调度节点 %s...", hostname))
+	if _, err := utils.Kubectl(kubeconfig, "cordon", hostname); err != nil {
+		return fmt.Errorf("failed to cordon node %s: %v", hostname, err)
+	}
+
+	reporter.ReportProgress(fmt.Sprintf("驱逐节点 %s 上的 Pod...", hostname))
+	args := []string{
+		"drain", hostname,
+		"--ignore-daemonsets",
+		"--delete-emptydir-data",
+		fmt.Sprintf("--grace-period=%d", options.GracePeriod),
+		fmt.Sprintf("--timeout=%ds", options.Timeout),
+	}
+	if options.DisableEviction {
+		args = append(args, "--disable-eviction")
+	}
+	if options.Force {
+		args = append(args, "--force")
+	}
+	if _, err := utils.Kubectl(kubeconfig, args...); err != nil {
+		if !options.Force {
+			return fmt.Errorf("failed to drain node %s, node is left cordoned: %v", hostname, err)
+		}
+		log.Printf("Failed to drain node %s, continuing: %v", hostname, err)
+	}
+
+	reporter.ReportProgress(fmt.Sprintf("删除节点 %s...", hostname))
+	if _, err := utils.Kubectl(kubeconfig, "delete", "node", hostname, "--ignore-not-found"); err != nil {
+		return fmt.Errorf("failed to delete node %s: %v", hostname, err)
+	}
+
+	// 主机可能已不可用，重置失败不影响从集群记录中移除
+	reporter.ReportProgress(fmt.Sprintf("重置主机 %s...", hostname))
+	if err := config.WriteAnsibleFiles(); err != nil {
+		return err
+	}
+	if err := utils.ExecuteAnsiblePlaybook("resetKubeadm", "--limit", hostname); err != nil {
+		log.Printf("Failed to reset host %s: %v", hostname, err)
+		reporter.ReportProgress(fmt.Sprintf("重置主机 %s 失败，请手动清理: %v", hostname, err))
+	}
+
+	reporter.ReportProgress("更新 Ansible inventory 与配置文件...")
+	config.Nodes = maps.Clone(config.Nodes)
+	delete(config.Nodes, hostname)
+	removeSecondaryIP(&config, hostname)
+	config.ApplyDefaults()
+	if err := config.WriteAnsibleFiles(); err != nil {
+		return err
+	}
+
+	// 节点已从集群中删除，先更新集群记录，再从其余主机的 /etc/hosts 中移除该节点
+	record.Config = config
+	if err := store.Save(record); err != nil {
+		return err
+	}
+
+	reporter.ReportProgress("执行initUpdateEtcHosts...")
+	if err := utils.ExecuteAnsiblePlaybook("initUpdateEtcHosts"); err != nil {
+		return fmt.Errorf("failed to execute playbook initUpdateEtcHosts: %v", err)
+	}
+
+	log.Printf("Node %s removed from cluster %s", hostname, record.ID)
+	return nil
+}
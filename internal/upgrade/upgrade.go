@@ -0,0 +1,198 @@
+package upgrade
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"KubeCraft/internal/addon"
+	"KubeCraft/internal/cluster"
+	"KubeCraft/internal/etcd"
+	"KubeCraft/internal/utils"
+)
+
+// DefaultBatchSize worker 节点每批升级的数量
+const DefaultBatchSize = 1
+
+// ProgressReporter 进度报告接口
+type ProgressReporter interface {
+	ReportProgress(message string)
+}
+
+// Request 升级请求
+type Request struct {
+	Version   string `json:"version"`   // 目标 Kubernetes 版本
+	BatchSize int    `json:"batchSize"` // worker 节点每批升级的数量
+}
+
+// Upgrade 滚动升级集群：先升级首个 Master，再依次升级其余 Master，然后分批升级 worker 节点，最后升级附加组件。
+// 每个节点升级后执行健康检查，失败时立即停止，结果记录在集群记录的 Upgrades 中。
+func Upgrade(store *cluster.Store, record *cluster.Record, req Request, reporter ProgressReporter) error {
+	config := record.Config
+	req.Version = strings.TrimPrefix(req.Version, "v")
+	if err := utils.CheckUpgrade(config.KubernetesVersion, req.Version); err != nil {
+		return err
+	}
+	if req.BatchSize <= 0 {
+		req.BatchSize = DefaultBatchSize
+	}
+
+	if err := config.UpgradeTo(req.Version); err != nil {
+		return err
+	}
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+
+	log.Printf("Upgrading cluster %s from %s to %s", record.ID, record.Config.KubernetesVersion, req.Version)
+	upgrade := cluster.Upgrade{
+		PreviousVersion: record.Config.KubernetesVersion,
+		Version:         req.Version,
+		Status:          cluster.StatusUpgrading,
+		StartedAt:       time.Now(),
+	}
+	record.Upgrades = append(record.Upgrades, upgrade)
+	record.Status = cluster.StatusUpgrading
+	if err := store.Save(record); err != nil {
+		return err
+	}
+
+	u := &upgrader{
+		kubeconfig: store.Kubeconfig(record.ID),
+		etcdPKIDir: store.EtcdPKIDir(record.ID),
+		config:     config,
+		reporter:   reporter,
+	}
+	err := u.run(req.BatchSize)
+
+	upgrade.Status = cluster.StatusReady
+	if err != nil {
+		upgrade.Status = cluster.StatusFailed
+		upgrade.Error = err.Error()
+	} else {
+		record.Config = config
+	}
+	upgrade.FinishedAt = time.Now()
+	record.Upgrades[len(record.Upgrades)-1] = upgrade
+	record.Status = upgrade.Status
+	if saveErr := store.Save(record); saveErr != nil {
+		log.Printf("Failed to save cluster %s: %v", record.ID, saveErr)
+	}
+
+	if err == nil {
+		log.Printf("Cluster %s upgraded to %s", record.ID, req.Version)
+	}
+	return err
+}
+
+// upgrader 保存升级过程中共享的状态
+type upgrader struct {
+	kubeconfig string
+	etcdPKIDir string // 外部 etcd 的证书目录
+	config     utils.Config
+	reporter   ProgressReporter
+}
+
+// run 按顺序升级所有节点
+func (u *upgrader) run(batchSize int) error {
+	u.reporter.ReportProgress("检查集群健康状态...")
+	if err := u.checkHealth(); err != nil {
+		return fmt.Errorf("cluster is unhealthy before upgrade: %v", err)
+	}
+
+	u.reporter.ReportProgress("生成 Ansible inventory 与配置文件...")
+	if err := u.config.WriteAnsibleFiles(); err != nil {
+		return err
+	}
+
+	// 更新所有主机的 kubernetes 软件源到目标版本
+	u.reporter.ReportProgress("执行initConfigureSoftwareSources...")
+	if err := utils.ExecuteAnsiblePlaybook("initConfigureSoftwareSources"); err != nil {
+		return fmt.Errorf("failed to execute playbook initConfigureSoftwareSources: %v", err)
+	}
+
+	// 首个 Master 执行 kubeadm upgrade apply，其余 Master 逐台执行 kubeadm upgrade node
+	masters := slices.Sorted(maps.Keys(u.config.Masters))
+	masters = slices.DeleteFunc(masters, func(host string) bool { return host == u.config.FirstMasterHostname })
+	masters = slices.Insert(masters, 0, u.config.FirstMasterHostname)
+	for _, host := range masters {
+		if err := u.upgradeBatch([]string{host}); err != nil {
+			return err
+		}
+	}
+
+	nodes := slices.Sorted(maps.Keys(u.config.Nodes))
+	for batch := range slices.Chunk(nodes, batchSize) {
+		if err := u.upgradeBatch(batch); err != nil {
+			return err
+		}
+	}
+
+	// 网络插件的兼容版本随 Kubernetes 次版本变化
+	return addon.UpgradeAll(addon.Context{Config: u.config, Kubeconfig: u.kubeconfig}, u.reporter)
+}
+
+// upgradeBatch 升级一批节点：升级 kubeadm、驱逐、升级 kubelet、恢复调度并等待就绪
+func (u *upgrader) upgradeBatch(hosts []string) error {
+	limit := strings.Join(hosts, ",")
+
+	u.reporter.ReportProgress(fmt.Sprintf("升级 %s 的 kubeadm 与节点配置...", limit))
+	if err := utils.ExecuteAnsiblePlaybook("upgradeKubernetes", "--limit", limit, "--tags", "kubeadm"); err != nil {
+		return fmt.Errorf("failed to upgrade kubeadm on %s: %v", limit, err)
+	}
+
+	for _, host := range hosts {
+		u.reporter.ReportProgress(fmt.Sprintf("驱逐节点 %s 上的 Pod...", host))
+		_, err := u.kubectl("drain", host, "--ignore-daemonsets", "--delete-emptydir-data", "--timeout=5m")
+		if err != nil {
+			return fmt.Errorf("failed to drain node %s: %v", host, err)
+		}
+	}
+
+	u.reporter.ReportProgress(fmt.Sprintf("升级 %s 的 kubelet 与 kubectl...", limit))
+	if err := utils.ExecuteAnsiblePlaybook("upgradeKubernetes", "--limit", limit, "--tags", "kubelet"); err != nil {
+		return fmt.Errorf("failed to upgrade kubelet on %s: %v", limit, err)
+	}
+
+	for _, host := range hosts {
+		u.reporter.ReportProgress(fmt.Sprintf("等待节点 %s 就绪...", host))
+		if _, err := u.kubectl("uncordon", host); err != nil {
+			return fmt.Errorf("failed to uncordon node %s: %v", host, err)
+		}
+		if _, err := u.kubectl("wait", "--for=condition=Ready", "node/"+host, "--timeout=5m"); err != nil {
+			return fmt.Errorf("node %s is not ready: %v", host, err)
+		}
+	}
+
+	if err := u.checkHealth(); err != nil {
+		return fmt.Errorf("health check failed after upgrading %s: %v", limit, err)
+	}
+	return nil
+}
+
+// checkHealth 检查 API Server 就绪以及所有 etcd 成员健康
+func (u *upgrader) checkHealth() error {
+	if _, err := u.kubectl("get", "--raw", "/readyz"); err != nil {
+		return fmt.Errorf("API server is not ready: %v", err)
+	}
+
+	client := etcd.NewClient(u.kubeconfig, u.config, u.config.FirstMasterHostname, u.etcdPKIDir)
+	members, err := client.Members()
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if !member.Healthy {
+			return fmt.Errorf("etcd member %s is unhealthy", member.Name)
+		}
+	}
+	return nil
+}
+
+// kubectl 使用集群 kubeconfig 执行 kubectl
+func (u *upgrader) kubectl(args ...string) (string, error) {
+	return utils.Kubectl(u.kubeconfig, args...)
+}
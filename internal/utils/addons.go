@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// 内置附加组件名称
+const (
+	AddonCNI          = "cni"
+	AddonNfsCsi       = "nfs-csi"
+	AddonMetalLB      = "metallb"
+	AddonIngressNginx = "ingress-nginx"
+)
+
+// addonNames 已注册的附加组件名称，由 addon.Register 登记，用于在部署前校验配置
+var addonNames []string
+
+// RegisterAddonName 登记附加组件名称
+func RegisterAddonName(name string) {
+	addonNames = append(addonNames, name)
+}
+
+// AddonConfig 单个附加组件的配置
+type AddonConfig struct {
+	Enabled *bool          `json:"enabled"` // 为空时使用默认值，见 AddonEnabled
+	Values  map[string]any `json:"values"`  // 通过 Helm 安装的组件覆盖生成的 values
+}
+
+// AddonEnabled 返回附加组件是否启用：未显式设置时 NFS CSI 仅在配置了 NFS 服务器时启用，其余组件默认启用
+func (config *Config) AddonEnabled(name string) bool {
+	if addon, ok := config.Addons[name]; ok && addon.Enabled != nil {
+		return *addon.Enabled
+	}
+	if name == AddonNfsCsi {
+		return config.NfsServerIP != ""
+	}
+	return true
+}
+
+// SetAddonEnabled 显式启用或禁用附加组件，保留其余设置
+func (config *Config) SetAddonEnabled(name string, enabled bool) {
+	if config.Addons == nil {
+		config.Addons = make(map[string]AddonConfig)
+	}
+	addon := config.Addons[name]
+	addon.Enabled = &enabled
+	config.Addons[name] = addon
+}
+
+// validateAddons 校验已启用附加组件所需的配置
+func (config *Config) validateAddons() error {
+	names := make([]string, 0, len(config.Addons))
+	for name := range config.Addons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !slices.Contains(addonNames, name) {
+			return fmt.Errorf("addons: unknown addon %q", name)
+		}
+	}
+
+	if config.AddonEnabled(AddonNfsCsi) && (config.NfsServerIP == "" || config.NfsDir == "") {
+		return fmt.Errorf("addons.%s: nfsServerIP and nfsDir are required", AddonNfsCsi)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// ConfigFile playbook 读取的配置文件，位于项目根目录
+const ConfigFile = "config.json"
+
+// PlaybookDir playbook 所在目录
+const PlaybookDir = "./ansiblePlaybook"
+
+// SaveToFile 将配置保存为 JSON 文件
+func (config *Config) SaveToFile(filename string) error {
+	// 创建或截断文件，配置包含 SSH 密码等凭据，仅 root 可读写
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create config file: %v", err)
+	}
+	defer file.Close()
+	// 已存在的文件保留原权限，需显式收紧
+	if err := file.Chmod(0600); err != nil {
+		return fmt.Errorf("failed to set config file permissions: %v", err)
+	}
+
+	// 将配置编码为JSON并写入文件
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // 美化输出
+	if err := encoder.Encode(config); err != nil {
+		return fmt.Errorf("failed to encode config to JSON: %v", err)
+	}
+
+	return nil
+}
+
+// WriteAnsibleFiles 写入 playbook 读取的 config.json 与默认位置 /etc/ansible/hosts 的 inventory。
+// 两者为全局文件，调用方需持有集群锁
+func (config *Config) WriteAnsibleFiles() error {
+	if err := config.SaveToFile(ConfigFile); err != nil {
+		return fmt.Errorf("failed to save config: %v", err)
+	}
+	if err := config.GenerateDefaultInventory(); err != nil {
+		return fmt.Errorf("failed to generate Ansible inventory: %v", err)
+	}
+	return nil
+}
+
+// ExecuteAnsiblePlaybook 执行指定的 Ansible Playbook，args 为附加的 ansible-playbook 参数，如 --limit
+func ExecuteAnsiblePlaybook(playbookName string, args ...string) error {
+	// 等待一段时间确保系统准备就绪
+	time.Sleep(1 * time.Second)
+
+	// 使用默认的 /etc/ansible/hosts 作为 inventory
+	cmd := exec.Command("ansible-playbook", append([]string{playbookName + ".yaml"}, args...)...)
+	cmd.Dir = PlaybookDir
+
+	// 执行命令并返回结果
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("command failed: %v, output: %s", err, string(output))
+	}
+
+	return nil
+}
+
+// Kubectl 执行 kubectl 命令并返回输出，kubeconfig 为空时使用默认配置
+func Kubectl(kubeconfig string, args ...string) (string, error) {
+	if kubeconfig != "" {
+		args = append([]string{"--kubeconfig", kubeconfig}, args...)
+	}
+
+	cmd := exec.Command("kubectl", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("kubectl %v failed: %v, output: %s", args, err, string(output))
+	}
+	return string(output), nil
+}
+
+// KubectlApply 将 manifest 编码为 JSON 写入临时文件并执行 kubectl apply
+func KubectlApply(kubeconfig string, manifest any) error {
+	file, err := os.CreateTemp("", "kubecraft-manifest-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create manifest file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := json.NewEncoder(file).Encode(manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+	_, err = Kubectl(kubeconfig, "apply", "-f", file.Name())
+	return err
+}
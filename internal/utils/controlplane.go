@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// 支持的控制面负载均衡方式
+const (
+	LBKeepalivedNginx   = "keepalived-nginx"   // keepalived VIP + nginx 四层代理，监听 8443
+	LBKeepalivedHAProxy = "keepalived-haproxy" // keepalived VIP + haproxy 四层代理，监听 8443
+	LBKubeVip           = "kube-vip"           // kube-vip 静态 Pod 通过 ARP 宣告 VIP，直接指向 6443
+	LBExternal          = "external"           // 使用已有的负载均衡，由 ExternalLBAddress 指定
+	LBNone              = "none"               // 不使用负载均衡，仅适用于单 Master 集群
+)
+
+// ControlPlaneLBs 支持的控制面负载均衡方式
+var ControlPlaneLBs = []string{LBKeepalivedNginx, LBKeepalivedHAProxy, LBKubeVip, LBExternal, LBNone}
+
+// 控制面默认端口
+const (
+	DefaultBindPort = 6443
+	DefaultLBPort   = 8443 // keepalived-nginx 与 keepalived-haproxy 的代理端口
+)
+
+// dnsNamePattern 证书 SAN 与入口域名的格式，允许通配符开头
+var dnsNamePattern = regexp.MustCompile(`^(\*\.)?([a-z0-9]([-a-z0-9]*[a-z0-9])?\.)*[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// DefaultKubeVipImage kube-vip 默认镜像
+const DefaultKubeVipImage = "ghcr.io/kube-vip/kube-vip:v0.8.9"
+
+// UsesKeepalived 判断控制面负载均衡是否由 keepalived 提供 VIP
+func (config *Config) UsesKeepalived() bool {
+	return config.ControlPlaneLB == LBKeepalivedNginx || config.ControlPlaneLB == LBKeepalivedHAProxy
+}
+
+// ControlPlaneURL 返回控制面入口的 API Server 地址
+func (config *Config) ControlPlaneURL() string {
+	endpoint := config.Resolved.ControlPlaneEndpoint
+	if endpoint == "" {
+		// 早期版本的集群记录只有 keepalived-nginx 方式
+		endpoint = net.JoinHostPort(config.KeepalivedVip, strconv.Itoa(DefaultLBPort))
+	}
+	return "https://" + endpoint
+}
+
+// applyControlPlaneDefaults 设置控制面负载均衡方式与端口，计算 controlPlaneEndpoint 与证书 SAN
+func (config *Config) applyControlPlaneDefaults() {
+	if config.ControlPlaneLB == "" {
+		config.ControlPlaneLB = LBKeepalivedNginx
+		if len(config.Masters) == 1 && config.KeepalivedVip == "" {
+			config.ControlPlaneLB = LBNone
+		}
+	}
+	config.Resolved.KubeVipImage = DefaultKubeVipImage
+
+	apiServer := &config.APIServer
+	if apiServer.BindPort == 0 {
+		apiServer.BindPort = DefaultBindPort
+	}
+	if apiServer.LBPort == 0 {
+		apiServer.LBPort = DefaultLBPort
+	}
+	apiServer.EndpointDNS = strings.ToLower(strings.TrimSuffix(apiServer.EndpointDNS, "."))
+	bindPort := strconv.Itoa(apiServer.BindPort)
+	lbPort := strconv.Itoa(apiServer.LBPort)
+
+	// 设置入口域名时 kubeconfig 与节点均通过域名访问，VIP 变更不影响已签发的证书与 kubeconfig
+	host := config.KeepalivedVip
+	if config.ControlPlaneLB == LBNone {
+		host = config.Masters[config.FirstMasterHostname]
+	}
+	if apiServer.EndpointDNS != "" {
+		host = apiServer.EndpointDNS
+	}
+
+	switch config.ControlPlaneLB {
+	case LBKeepalivedNginx, LBKeepalivedHAProxy:
+		config.Resolved.ControlPlaneEndpoint = net.JoinHostPort(host, lbPort)
+	case LBKubeVip, LBNone:
+		config.Resolved.ControlPlaneEndpoint = net.JoinHostPort(host, bindPort)
+	case LBExternal:
+		config.Resolved.ControlPlaneEndpoint = withDefaultPort(config.ExternalLBAddress, bindPort)
+	default:
+		config.Resolved.ControlPlaneEndpoint = ""
+	}
+
+	// kubeadm 会自动加入 controlPlaneEndpoint 的主机，这里补充 VIP 与入口域名，保证两种方式都能通过证书校验
+	var sans []string
+	if config.UsesKeepalived() || config.ControlPlaneLB == LBKubeVip {
+		sans = append(sans, config.KeepalivedVip)
+	}
+	if apiServer.EndpointDNS != "" {
+		sans = append(sans, apiServer.EndpointDNS)
+	}
+	for _, san := range apiServer.CertSANs {
+		if san = strings.ToLower(strings.TrimSpace(san)); san != "" {
+			sans = append(sans, san)
+		}
+	}
+	slices.Sort(sans)
+	config.Resolved.CertSANs = slices.Compact(sans)
+}
+
+// validateControlPlaneLB 校验控制面负载均衡方式所需的配置
+func (config *Config) validateControlPlaneLB() error {
+	if !slices.Contains(ControlPlaneLBs, config.ControlPlaneLB) {
+		return fmt.Errorf("controlPlaneLB: unsupported load balancer %q, supported: %s",
+			config.ControlPlaneLB, strings.Join(ControlPlaneLBs, ", "))
+	}
+
+	switch config.ControlPlaneLB {
+	case LBKeepalivedNginx, LBKeepalivedHAProxy, LBKubeVip:
+		if net.ParseIP(config.KeepalivedVip) == nil {
+			return fmt.Errorf("keepalivedVip: %q is not a valid IP address, required by %s", config.KeepalivedVip, config.ControlPlaneLB)
+		}
+	case LBExternal:
+		if config.ExternalLBAddress == "" {
+			return fmt.Errorf("externalLBAddress: required by %s", LBExternal)
+		}
+		if _, _, err := net.SplitHostPort(config.Resolved.ControlPlaneEndpoint); err != nil {
+			return fmt.Errorf("externalLBAddress: %v", err)
+		}
+	case LBNone:
+		if len(config.Masters) != 1 {
+			return fmt.Errorf("controlPlaneLB: %s only supports a single master, got %d", LBNone, len(config.Masters))
+		}
+	}
+	return config.validateAPIServer()
+}
+
+// validateAPIServer 校验 API Server 端口与证书 SAN
+func (config *Config) validateAPIServer() error {
+	apiServer := config.APIServer
+	for _, port := range []struct {
+		field string
+		value int
+	}{{"apiServer.bindPort", apiServer.BindPort}, {"apiServer.lbPort", apiServer.LBPort}} {
+		if port.value < 1 || port.value > 65535 {
+			return fmt.Errorf("%s: %d is out of range 1-65535", port.field, port.value)
+		}
+	}
+	// 代理与 API Server 运行在同一台 Master 上
+	if config.UsesKeepalived() && apiServer.BindPort == apiServer.LBPort {
+		return fmt.Errorf("apiServer.lbPort: must differ from apiServer.bindPort %d with %s", apiServer.BindPort, config.ControlPlaneLB)
+	}
+
+	if dns := apiServer.EndpointDNS; dns != "" && (strings.HasPrefix(dns, "*") || !dnsNamePattern.MatchString(dns)) {
+		return fmt.Errorf("apiServer.endpointDNS: invalid DNS name %q", apiServer.EndpointDNS)
+	}
+	for _, san := range config.Resolved.CertSANs {
+		if net.ParseIP(san) == nil && !dnsNamePattern.MatchString(san) {
+			return fmt.Errorf("apiServer.certSANs: %q is neither an IP address nor a DNS name", san)
+		}
+	}
+	return nil
+}
+
+// withDefaultPort 为未指定端口的地址补充默认端口
+func withDefaultPort(address, port string) string {
+	if address == "" {
+		return ""
+	}
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	return net.JoinHostPort(strings.Trim(address, "[]"), port)
+}
@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"maps"
+	"math/big"
+	"net"
+	"slices"
+	"strings"
+)
+
+// 支持的容器运行时
+const (
+	RuntimeContainerd = "containerd"
+	RuntimeCRIO       = "cri-o"
+)
+
+// CRISockets 各容器运行时的 CRI 地址
+var CRISockets = map[string]string{
+	RuntimeContainerd: "unix:///var/run/containerd/containerd.sock",
+	RuntimeCRIO:       "unix:///var/run/crio/crio.sock",
+}
+
+// 默认的软件源与镜像仓库
+const (
+	DefaultBaseOSRepo      = "https://mirrors.aliyun.com/repo/Centos-7.repo"
+	DefaultEPELRepo        = "http://mirrors.aliyun.com/repo/epel-7.repo"
+	DefaultKubernetesRepo  = "https://mirrors.aliyun.com/kubernetes-new/core/stable/"
+	DefaultCRIORepo        = "https://mirrors.aliyun.com/kubernetes-new/addons/cri-o/stable/"
+	DefaultImageRepository = "registry.aliyuncs.com/google_containers"
+	DefaultGitHubMirror    = "https://hub.gitmirror.com/https://github.com"
+	DefaultContainerdRoot  = "/var/lib/containerd"
+)
+
+// ApplyDefaults 为未填写的配置项设置默认值，需在写入 config.json 之前调用
+func (config *Config) ApplyDefaults() {
+	config.applyMirrorDefaults()
+	config.applyVersionDefaults()
+	config.applyContainerdDefaults()
+	config.applyRuntimeDefaults()
+	config.applyNetworkDefaults()
+	config.applyControlPlaneDefaults()
+	config.applyEtcdDefaults()
+	config.applyKeepalivedDefaults()
+	config.applyMetalLBDefaults()
+}
+
+// applyVersionDefaults 设置 Kubernetes 版本并根据兼容矩阵填充相关配置
+func (config *Config) applyVersionDefaults() {
+	config.KubernetesVersion = strings.TrimPrefix(config.KubernetesVersion, "v")
+	if config.KubernetesVersion == "" {
+		config.KubernetesVersion = DefaultKubernetesVersion
+	}
+
+	// 不支持的版本留给 Validate 报错
+	info, err := LookupVersion(config.KubernetesVersion)
+	if err != nil {
+		return
+	}
+	config.Resolved.KubeadmAPIVersion = info.KubeadmAPIVersion
+	config.Resolved.PackageRepoPath = info.PackageRepoPath
+	config.Resolved.EtcdVersion = info.EtcdVersion
+	config.Resolved.PauseImage = config.Mirrors.ImageRepository + "/" + info.PauseImage
+}
+
+// applyMirrorDefaults 设置软件源默认值，启用制品服务器时由制品仓库提供软件包
+func (config *Config) applyMirrorDefaults() {
+	config.Artifacts.URL = strings.TrimSuffix(config.Artifacts.URL, "/")
+
+	mirrors := &config.Mirrors
+	mirrors.GitHub = strings.TrimSuffix(mirrors.GitHub, "/")
+	mirrors.ImageRepository = strings.TrimSuffix(mirrors.ImageRepository, "/")
+	mirrors.HelmRepo = strings.TrimSuffix(mirrors.HelmRepo, "/")
+
+	if mirrors.ImageRepository == "" {
+		mirrors.ImageRepository = DefaultImageRepository
+	}
+	if mirrors.GitHub == "" {
+		mirrors.GitHub = DefaultGitHubMirror
+	}
+
+	if config.Artifacts.Enabled {
+		return
+	}
+	if mirrors.PackageRepos.BaseOS == "" {
+		mirrors.PackageRepos.BaseOS = DefaultBaseOSRepo
+	}
+	if mirrors.PackageRepos.EPEL == "" {
+		mirrors.PackageRepos.EPEL = DefaultEPELRepo
+	}
+	if mirrors.PackageRepos.Kubernetes == "" {
+		mirrors.PackageRepos.Kubernetes = DefaultKubernetesRepo
+	}
+	mirrors.PackageRepos.Kubernetes = strings.TrimSuffix(mirrors.PackageRepos.Kubernetes, "/") + "/"
+	if mirrors.PackageRepos.CRIO == "" {
+		mirrors.PackageRepos.CRIO = DefaultCRIORepo
+	}
+	mirrors.PackageRepos.CRIO = strings.TrimSuffix(mirrors.PackageRepos.CRIO, "/") + "/"
+}
+
+// applyRuntimeDefaults 设置容器运行时及对应的 CRI 地址
+func (config *Config) applyRuntimeDefaults() {
+	if config.ContainerRuntime == "" {
+		config.ContainerRuntime = RuntimeContainerd
+	}
+	config.Resolved.CRISocket = CRISockets[config.ContainerRuntime]
+}
+
+// applyContainerdDefaults 设置 containerd 默认值，并合并镜像加速与非安全仓库配置
+func (config *Config) applyContainerdDefaults() {
+	containerd := &config.Containerd
+	containerd.Version = strings.TrimPrefix(containerd.Version, "v")
+	if containerd.DataRoot == "" {
+		containerd.DataRoot = DefaultContainerdRoot
+	}
+	if containerd.SandboxImage == "" {
+		containerd.SandboxImage = config.Resolved.PauseImage
+	}
+	if info, err := LookupVersion(config.KubernetesVersion); err == nil && containerd.Version == "" {
+		containerd.Version = info.ContainerdVersions[0]
+	}
+
+	registries := make([]RegistryMirror, 0, len(config.Mirrors.Registries)+len(containerd.InsecureRegistries))
+	index := make(map[string]int)
+	for _, registry := range config.Mirrors.Registries {
+		registry.Endpoints = slices.Clone(registry.Endpoints)
+		if len(registry.Endpoints) == 0 {
+			registry.Endpoints = []string{registryServer(registry.Registry)}
+		}
+		index[registry.Registry] = len(registries)
+		registries = append(registries, registry)
+	}
+
+	// 非安全仓库同时允许 HTTPS（跳过校验）与 HTTP 访问
+	for _, host := range containerd.InsecureRegistries {
+		host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+		i, ok := index[host]
+		if !ok {
+			i = len(registries)
+			index[host] = i
+			registries = append(registries, RegistryMirror{Registry: host, Endpoints: []string{registryServer(host)}})
+		}
+		registries[i].SkipVerify = true
+		registries[i].Endpoints = append(registries[i].Endpoints, "http://"+host)
+	}
+	config.Resolved.Registries = registries
+}
+
+// registryServer 返回仓库的默认访问地址
+func registryServer(registry string) string {
+	if registry == "docker.io" {
+		return "https://registry-1.docker.io"
+	}
+	return "https://" + registry
+}
+
+// applyKeepalivedDefaults 设置 VRID 与认证密码，并为 Master 分配递减的 VRRP 优先级
+func (config *Config) applyKeepalivedDefaults() {
+	if !config.UsesKeepalived() {
+		config.Resolved.KeepalivedPriority = nil
+		return
+	}
+
+	keepalived := &config.Keepalived
+	if keepalived.VirtualRouterID == 0 {
+		keepalived.VirtualRouterID = defaultVirtualRouterID(config.KeepalivedVip)
+	}
+	// 生成失败时保持为空，由 Validate 报错
+	if keepalived.AuthPass == "" {
+		password, err := RandomString(passwordCharset, 8)
+		if err != nil {
+			log.Printf("Failed to generate keepalived password: %v", err)
+		}
+		keepalived.AuthPass = password
+	}
+
+	// 首个 Master 优先级最高，其余按主机名排序依次递减
+	priority := make(map[string]int, len(config.Masters))
+	priority[config.FirstMasterHostname] = 100
+	next := 90
+	for _, hostname := range slices.Sorted(maps.Keys(config.Masters)) {
+		if hostname != config.FirstMasterHostname {
+			priority[hostname] = max(next, 1)
+			next -= 10
+		}
+	}
+	config.Resolved.KeepalivedPriority = priority
+}
+
+// defaultVirtualRouterID 取 VIP 最后一段作为 VRID，无法解析时使用 51
+func defaultVirtualRouterID(vip string) int {
+	ip := net.ParseIP(vip).To4()
+	if ip == nil || ip[3] == 0 {
+		return 51
+	}
+	return int(ip[3])
+}
+
+// passwordCharset 自动生成的密码使用的字符
+const passwordCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// RandomString 生成由 charset 中字符组成的长度为 n 的随机字符串，各字符出现概率相同
+func RandomString(charset string, n int) (string, error) {
+	var builder strings.Builder
+	max := big.NewInt(int64(len(charset)))
+	for i := 0; i < n; i++ {
+		index, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random string: %v", err)
+		}
+		builder.WriteByte(charset[index.Int64()])
+	}
+	return builder.String(), nil
+}
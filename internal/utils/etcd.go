@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+)
+
+// 支持的 etcd 拓扑
+const (
+	EtcdStacked  = "stacked"
+	EtcdExternal = "external"
+)
+
+// DefaultEtcdDataDir 外部 etcd 的默认数据目录
+const DefaultEtcdDataDir = "/var/lib/etcd"
+
+// IsExternalEtcd 判断集群是否使用外部 etcd
+func (config *Config) IsExternalEtcd() bool {
+	return config.Etcd.Topology == EtcdExternal
+}
+
+// applyEtcdDefaults 设置 etcd 拓扑并计算外部 etcd 的客户端地址
+func (config *Config) applyEtcdDefaults() {
+	etcd := &config.Etcd
+	if etcd.Topology == "" {
+		etcd.Topology = EtcdStacked
+	}
+
+	config.Resolved.EtcdEndpoints = nil
+	if etcd.Topology != EtcdExternal {
+		return
+	}
+	if etcd.DataDir == "" {
+		etcd.DataDir = DefaultEtcdDataDir
+	}
+	for _, hostname := range slices.Sorted(maps.Keys(etcd.Hosts)) {
+		endpoint := "https://" + net.JoinHostPort(etcd.Hosts[hostname], "2379")
+		config.Resolved.EtcdEndpoints = append(config.Resolved.EtcdEndpoints, endpoint)
+	}
+}
+
+// validateEtcd 校验 etcd 拓扑配置
+func (config *Config) validateEtcd() error {
+	etcd := config.Etcd
+	switch etcd.Topology {
+	case EtcdStacked:
+		return nil
+	case EtcdExternal:
+	default:
+		return fmt.Errorf("etcd.topology: unsupported topology %q, supported: %s, %s", etcd.Topology, EtcdStacked, EtcdExternal)
+	}
+
+	if len(etcd.Hosts) == 0 {
+		return fmt.Errorf("etcd.hosts: at least one host is required by %s topology", EtcdExternal)
+	}
+	if len(etcd.Hosts)%2 == 0 {
+		return fmt.Errorf("etcd.hosts: got %d hosts, the number of etcd members must be odd", len(etcd.Hosts))
+	}
+	for hostname, ip := range etcd.Hosts {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("etcd.hosts: %q of %s is not a valid IP address", ip, hostname)
+		}
+		// Kubernetes 节点会执行 kubeadm reset 等操作，不能与 etcd 主机共用
+		if _, ok := config.Masters[hostname]; ok {
+			return fmt.Errorf("etcd.hosts: %s is also a master", hostname)
+		}
+		if _, ok := config.Nodes[hostname]; ok {
+			return fmt.Errorf("etcd.hosts: %s is also a node", hostname)
+		}
+	}
+	return nil
+}
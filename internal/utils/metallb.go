@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// MetalLB 地址宣告方式
+const (
+	MetalLBModeL2  = "l2"
+	MetalLBModeBGP = "bgp"
+)
+
+// DefaultMetalLBPool 由 LoadBalancerIP 生成的地址池名称
+const DefaultMetalLBPool = "default"
+
+// DefaultBGPPort BGP 对端默认端口
+const DefaultBGPPort = 179
+
+// metalLBNamePattern 地址池与 BGP 对端名称，需符合 Kubernetes 资源名称
+var metalLBNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// MetalLBConfig MetalLB 地址池与宣告方式配置
+type MetalLBConfig struct {
+	Mode  string        `json:"mode"`  // l2 或 bgp，默认 l2
+	Pools []MetalLBPool `json:"pools"` // 为空时由 LoadBalancerIP 生成名为 default 的地址池
+	Peers []BGPPeer     `json:"peers"` // bgp 模式下建立会话的路由器
+}
+
+// MetalLBPool MetalLB IPAddressPool
+type MetalLBPool struct {
+	Name       string   `json:"name"`
+	Addresses  []string `json:"addresses"`  // CIDR 或 起始-结束 格式
+	AutoAssign *bool    `json:"autoAssign"` // 为 false 时仅分配给显式指定该地址池的 Service，默认 true
+}
+
+// BGPPeer MetalLB BGP 对端
+type BGPPeer struct {
+	Name     string `json:"name"`     // 为空时使用 peer-<序号>
+	Address  string `json:"address"`  // 对端地址
+	ASN      uint32 `json:"asn"`      // 对端 AS 号
+	MyASN    uint32 `json:"myASN"`    // 集群使用的 AS 号
+	Port     int    `json:"port"`     // 默认 179
+	Password string `json:"password"` // TCP MD5 认证密码，可选
+}
+
+// applyMetalLBDefaults 设置宣告方式与 BGP 对端默认值，并计算实际使用的地址池
+func (config *Config) applyMetalLBDefaults() {
+	metalLB := &config.MetalLB
+	metalLB.Mode = strings.ToLower(metalLB.Mode)
+	if metalLB.Mode == "" {
+		metalLB.Mode = MetalLBModeL2
+	}
+	for i := range metalLB.Peers {
+		peer := &metalLB.Peers[i]
+		if peer.Name == "" {
+			peer.Name = fmt.Sprintf("peer-%d", i+1)
+		}
+		if peer.Port == 0 {
+			peer.Port = DefaultBGPPort
+		}
+	}
+
+	config.Resolved.MetalLBPools = metalLB.Pools
+	if len(metalLB.Pools) == 0 && config.LoadBalancerIP != "" {
+		config.Resolved.MetalLBPools = []MetalLBPool{{Name: DefaultMetalLBPool, Addresses: splitList(config.LoadBalancerIP)}}
+	}
+}
+
+// validateMetalLB 校验地址池与 BGP 对端，地址池的地址族需属于集群地址族
+func (config *Config) validateMetalLB() error {
+	metalLB := config.MetalLB
+	if metalLB.Mode != MetalLBModeL2 && metalLB.Mode != MetalLBModeBGP {
+		return fmt.Errorf("metalLB.mode: unsupported mode %q, supported: %s, %s", metalLB.Mode, MetalLBModeL2, MetalLBModeBGP)
+	}
+
+	// 未配置 pools 时地址来自 loadBalancerIP，错误信息沿用该字段名
+	field := "loadBalancerIP"
+	if len(metalLB.Pools) > 0 {
+		field = "metalLB.pools"
+	}
+	seen := make(map[string]bool)
+	for _, pool := range config.Resolved.MetalLBPools {
+		if !metalLBNamePattern.MatchString(pool.Name) {
+			return fmt.Errorf("%s: invalid pool name %q", field, pool.Name)
+		}
+		if seen[pool.Name] {
+			return fmt.Errorf("%s: duplicate pool name %q", field, pool.Name)
+		}
+		seen[pool.Name] = true
+		if len(pool.Addresses) == 0 {
+			return fmt.Errorf("%s: pool %s has no addresses", field, pool.Name)
+		}
+		for _, address := range pool.Addresses {
+			if err := config.validatePoolAddress(field, address); err != nil {
+				return err
+			}
+		}
+	}
+
+	if metalLB.Mode != MetalLBModeBGP {
+		if len(metalLB.Peers) > 0 {
+			return fmt.Errorf("metalLB.peers: BGP peers require mode %s", MetalLBModeBGP)
+		}
+		return nil
+	}
+	if len(metalLB.Peers) == 0 && config.AddonEnabled(AddonMetalLB) {
+		return fmt.Errorf("metalLB.peers: at least one BGP peer is required in %s mode", MetalLBModeBGP)
+	}
+	seen = make(map[string]bool)
+	for _, peer := range metalLB.Peers {
+		switch {
+		case !metalLBNamePattern.MatchString(peer.Name):
+			return fmt.Errorf("metalLB.peers: invalid peer name %q", peer.Name)
+		case seen[peer.Name]:
+			return fmt.Errorf("metalLB.peers: duplicate peer name %q", peer.Name)
+		case IPFamily(peer.Address) == "":
+			return fmt.Errorf("metalLB.peers: %s has invalid address %q", peer.Name, peer.Address)
+		case peer.ASN == 0 || peer.MyASN == 0:
+			return fmt.Errorf("metalLB.peers: %s requires asn and myASN", peer.Name)
+		case peer.Port < 1 || peer.Port > 65535:
+			return fmt.Errorf("metalLB.peers: port %d of %s is out of range 1-65535", peer.Port, peer.Name)
+		}
+		seen[peer.Name] = true
+	}
+	return nil
+}
+
+// validatePoolAddress 校验地址池中的一项，支持 CIDR 与 起始-结束 两种格式
+func (config *Config) validatePoolAddress(field, address string) error {
+	var family string
+	if start, end, ok := strings.Cut(address, "-"); ok {
+		from, err1 := netip.ParseAddr(strings.TrimSpace(start))
+		to, err2 := netip.ParseAddr(strings.TrimSpace(end))
+		if err1 != nil || err2 != nil || from.Is4() != to.Is4() || to.Less(from) {
+			return fmt.Errorf("%s: invalid address range %q", field, address)
+		}
+		family = IPFamily(from.String())
+	} else {
+		prefix, err := netip.ParsePrefix(address)
+		if err != nil {
+			return fmt.Errorf("%s: invalid CIDR %q", field, address)
+		}
+		family = IPFamily(prefix.Addr().String())
+	}
+
+	if !slices.Contains(config.Resolved.IPFamilies, family) {
+		return fmt.Errorf("%s: %s pool %q does not match the cluster IP families %s",
+			field, family, address, strings.Join(config.Resolved.IPFamilies, ","))
+	}
+	return nil
+}
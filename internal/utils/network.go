@@ -0,0 +1,238 @@
+package utils
+
+import (
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// 地址族
+const (
+	IPv4 = "IPv4"
+	IPv6 = "IPv6"
+)
+
+// 网络插件
+const (
+	CNICilium  = "cilium"
+	CNICalico  = "calico"
+	CNIFlannel = "flannel"
+)
+
+// CNIs 支持的网络插件
+var CNIs = []string{CNICilium, CNICalico, CNIFlannel}
+
+// cniIPFamilies 各网络插件支持的地址族
+var cniIPFamilies = map[string][]string{
+	CNICilium:  {IPv4, IPv6},
+	CNICalico:  {IPv4, IPv6},
+	CNIFlannel: {IPv4, IPv6},
+}
+
+// DefaultCNI 默认的网络插件
+const DefaultCNI = CNICilium
+
+// kube-proxy 模式
+const (
+	KubeProxyIPVS     = "ipvs"
+	KubeProxyIPTables = "iptables"
+	KubeProxyNone     = "none" // 不部署 kube-proxy，由 Cilium 的 kubeProxyReplacement 处理 Service 转发
+)
+
+// KubeProxyModes 支持的 kube-proxy 模式
+var KubeProxyModes = []string{KubeProxyIPVS, KubeProxyIPTables, KubeProxyNone}
+
+// cniKubeProxyReplacement 可以替代 kube-proxy 的网络插件
+var cniKubeProxyReplacement = []string{CNICilium}
+
+// IPFamily 返回地址所属的地址族，无法解析时返回空
+func IPFamily(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	switch {
+	case err != nil:
+		return ""
+	case addr.Is4() || addr.Is4In6():
+		return IPv4
+	default:
+		return IPv6
+	}
+}
+
+// splitList 拆分逗号分隔的列表并去除空白
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseCIDRs 解析逗号分隔的 CIDR 列表，返回各 CIDR 的地址族
+func parseCIDRs(field, value string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range splitList(value) {
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid CIDR %q", field, item)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	switch {
+	case len(prefixes) == 0:
+		return nil, fmt.Errorf("%s: at least one CIDR is required", field)
+	case len(prefixes) > 2:
+		return nil, fmt.Errorf("%s: at most one IPv4 and one IPv6 CIDR are allowed", field)
+	case len(prefixes) == 2 && prefixes[0].Addr().Is4() == prefixes[1].Addr().Is4():
+		return nil, fmt.Errorf("%s: dual-stack CIDRs must be of different IP families", field)
+	}
+	return prefixes, nil
+}
+
+// applyNetworkDefaults 计算集群地址族、CoreDNS 地址与各主机的 node-ip
+func (config *Config) applyNetworkDefaults() {
+	config.PodNetwork = strings.Join(splitList(config.PodNetwork), ",")
+	config.ServiceNetwork = strings.Join(splitList(config.ServiceNetwork), ",")
+	if config.KubeProxyMode == "" {
+		config.KubeProxyMode = KubeProxyIPVS
+	}
+	config.CNI = strings.ToLower(config.CNI)
+	if config.CNI == "" {
+		config.CNI = DefaultCNI
+	}
+	config.Resolved.CNIVersion = ""
+	if info, err := LookupVersion(config.KubernetesVersion); err == nil {
+		config.Resolved.CNIVersion = info.CNIVersions[config.CNI]
+	}
+
+	// 无法解析的网段留给 Validate 报错
+	config.Resolved.IPFamilies = nil
+	config.Resolved.ClusterDNS = ""
+	if services, err := parseCIDRs("serviceNetwork", config.ServiceNetwork); err == nil {
+		for _, prefix := range services {
+			config.Resolved.IPFamilies = append(config.Resolved.IPFamilies, IPFamily(prefix.Addr().String()))
+		}
+		config.Resolved.ClusterDNS = nthAddr(services[0], 10).String()
+	}
+
+	config.Resolved.NodeIPs = make(map[string]string, len(config.Masters)+len(config.Nodes))
+	for _, hosts := range []map[string]string{config.Masters, config.Nodes} {
+		for hostname, ip := range hosts {
+			nodeIP := ip
+			if secondary := config.SecondaryIPs[hostname]; secondary != "" && len(config.Resolved.IPFamilies) == 2 {
+				nodeIP += "," + secondary
+			}
+			config.Resolved.NodeIPs[hostname] = nodeIP
+		}
+	}
+}
+
+// nthAddr 返回网段中的第 n 个地址
+func nthAddr(prefix netip.Prefix, n int) netip.Addr {
+	addr := prefix.Addr()
+	for range n {
+		addr = addr.Next()
+	}
+	return addr
+}
+
+// validateNetwork 校验网段、主机地址与 VIP 的地址族是否一致，以及网络插件是否支持所选地址族
+func (config *Config) validateNetwork() error {
+	services, err := parseCIDRs("serviceNetwork", config.ServiceNetwork)
+	if err != nil {
+		return err
+	}
+	pods, err := parseCIDRs("podNetwork", config.PodNetwork)
+	if err != nil {
+		return err
+	}
+	if len(pods) != len(services) {
+		return fmt.Errorf("podNetwork and serviceNetwork must both be single-stack or both be dual-stack")
+	}
+	for i := range pods {
+		if pods[i].Addr().Is4() != services[i].Addr().Is4() {
+			return fmt.Errorf("podNetwork and serviceNetwork must list IP families in the same order")
+		}
+		if pods[i].Overlaps(services[i]) {
+			return fmt.Errorf("podNetwork %s overlaps serviceNetwork %s", pods[i], services[i])
+		}
+	}
+
+	families := config.Resolved.IPFamilies
+	primary := families[0]
+	dualStack := len(families) == 2
+
+	// 主机的主地址需属于主地址族，双栈时每台主机都需要另一地址族的地址
+	for _, hosts := range []map[string]string{config.Masters, config.Nodes} {
+		for _, hostname := range slices.Sorted(maps.Keys(hosts)) {
+			ip := hosts[hostname]
+			if family := IPFamily(ip); family != primary {
+				return fmt.Errorf("%s: address %q must be %s, the primary IP family of serviceNetwork", hostname, ip, primary)
+			}
+			secondary := config.SecondaryIPs[hostname]
+			switch {
+			case dualStack && secondary == "":
+				return fmt.Errorf("secondaryIPs: %s requires an %s address for dual-stack", hostname, families[1])
+			case dualStack && IPFamily(secondary) != families[1]:
+				return fmt.Errorf("secondaryIPs: address %q of %s must be %s", secondary, hostname, families[1])
+			case !dualStack && secondary != "":
+				return fmt.Errorf("secondaryIPs: %s has a secondary address but the cluster is single-stack", hostname)
+			}
+		}
+	}
+
+	// keepalived 与 kube-vip 在 Master 的主地址所在网络上宣告 VIP
+	if config.UsesKeepalived() || config.ControlPlaneLB == LBKubeVip {
+		if family := IPFamily(config.KeepalivedVip); family != primary {
+			return fmt.Errorf("keepalivedVip: %q must be %s, the IP family of the masters", config.KeepalivedVip, primary)
+		}
+	}
+
+	return config.validateCNI(pods)
+}
+
+// validateCNI 校验网络插件是否支持集群地址族、kube-proxy 模式与 Pod 网段大小
+func (config *Config) validateCNI(pods []netip.Prefix) error {
+	cniFamilies, ok := cniIPFamilies[config.CNI]
+	if !ok {
+		return fmt.Errorf("cni: unsupported CNI %q, supported: %s", config.CNI, strings.Join(CNIs, ", "))
+	}
+	for _, family := range config.Resolved.IPFamilies {
+		if !slices.Contains(cniFamilies, family) {
+			return fmt.Errorf("cni: %s does not support %s", config.CNI, family)
+		}
+	}
+
+	if !slices.Contains(KubeProxyModes, config.KubeProxyMode) {
+		return fmt.Errorf("kubeProxyMode: unsupported mode %q, supported: %s", config.KubeProxyMode, strings.Join(KubeProxyModes, ", "))
+	}
+	if config.KubeProxyMode == KubeProxyNone && !slices.Contains(cniKubeProxyReplacement, config.CNI) {
+		return fmt.Errorf("kubeProxyMode: %s requires a CNI that replaces kube-proxy, %s does not", KubeProxyNone, config.CNI)
+	}
+
+	// 各节点的 Pod 子网由网段划分，网段需大于单个节点的子网
+	for _, pod := range pods {
+		if size := PodMaskSize(config.CNI, pod); pod.Bits() >= size {
+			return fmt.Errorf("podNetwork: %s is too small for %s, the prefix must be shorter than /%d", pod, config.CNI, size)
+		}
+	}
+	return nil
+}
+
+// PodMaskSize 返回网络插件为每个节点分配的 Pod 子网掩码长度，Calico 按 IP 块划分，其余按 /24 与 /120 划分
+func PodMaskSize(cni string, pod netip.Prefix) int {
+	switch {
+	case cni == CNICalico && pod.Addr().Is4():
+		return 26
+	case cni == CNICalico:
+		return 122
+	case pod.Addr().Is4():
+		return 24
+	default:
+		return 120
+	}
+}
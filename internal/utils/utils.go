@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
@@ -11,20 +13,119 @@ import (
 
 // Config 结构体定义配置参数
 type Config struct {
-	Masters             map[string]string `json:"masters"`
-	Nodes               map[string]string `json:"nodes"`
-	FirstMasterHostname string            `json:"firstMasterHostname"`
-	SshUser             string            `json:"sshUser"`
-	SshPass             string            `json:"sshPass"`
-	SshPort             int               `json:"sshPort"`
-	NetworkAdapter      string            `json:"networkAdapter"`
-	KeepalivedVip       string            `json:"keepalivedVip"`
-	ServiceNetwork      string            `json:"serviceNetwork"`
-	PodNetwork          string            `json:"podNetwork"`
-	LoadBalancerIP      string            `json:"loadBalancerIP"`
-	NfsDir              string            `json:"nfsDir"`
-	NfsServerIP         string            `json:"nfsServerIP"`
-	OsType              string            `json:"osType"`
+	Masters             map[string]string      `json:"masters"`
+	Nodes               map[string]string      `json:"nodes"`
+	FirstMasterHostname string                 `json:"firstMasterHostname"`
+	SshUser             string                 `json:"sshUser"`
+	SshPass             string                 `json:"sshPass"`
+	SshPort             int                    `json:"sshPort"`
+	NetworkAdapter      string                 `json:"networkAdapter"`
+	KeepalivedVip       string                 `json:"keepalivedVip"`
+	ControlPlaneLB      string                 `json:"controlPlaneLB"`    // 控制面负载均衡方式，见 LBKeepalivedNginx 等常量
+	ExternalLBAddress   string                 `json:"externalLBAddress"` // external 方式下已有负载均衡的地址，如 lb.example.com:6443
+	APIServer           APIServerConfig        `json:"apiServer"`
+	ServiceNetwork      string                 `json:"serviceNetwork"` // 双栈时以逗号分隔两个地址族的 CIDR，第一个为主地址族
+	PodNetwork          string                 `json:"podNetwork"`     // 双栈时以逗号分隔两个地址族的 CIDR，顺序需与 ServiceNetwork 一致
+	SecondaryIPs        map[string]string      `json:"secondaryIPs"`   // 双栈时各主机另一地址族的 IP，主机名到 IP
+	CNI                 string                 `json:"cni"`            // 网络插件，cilium、calico 或 flannel，默认 cilium
+	KubeProxyMode       string                 `json:"kubeProxyMode"`  // ipvs、iptables 或 none，none 时由 Cilium 替代 kube-proxy，默认 ipvs
+	LoadBalancerIP      string                 `json:"loadBalancerIP"` // MetalLB 默认地址池，逗号分隔的 CIDR 或 起始-结束，MetalLB.Pools 为空时使用
+	MetalLB             MetalLBConfig          `json:"metalLB"`
+	NfsDir              string                 `json:"nfsDir"`
+	NfsServerIP         string                 `json:"nfsServerIP"`
+	OsType              string                 `json:"osType"`
+	Artifacts           ArtifactsConfig        `json:"artifacts"`
+	Mirrors             MirrorsConfig          `json:"mirrors"`
+	KubernetesVersion   string                 `json:"kubernetesVersion"`
+	ContainerRuntime    string                 `json:"containerRuntime"` // containerd 或 cri-o，默认 containerd
+	Containerd          ContainerdConfig       `json:"containerd"`
+	Keepalived          KeepalivedConfig       `json:"keepalived"`
+	Etcd                EtcdConfig             `json:"etcd"`
+	Addons              map[string]AddonConfig `json:"addons"` // 附加组件的启用状态与设置，组件名到配置
+	Resolved            ResolvedConfig         `json:"resolved"`
+}
+
+// ContainerdConfig containerd 配置，镜像加速和仓库认证使用 Mirrors.Registries
+type ContainerdConfig struct {
+	Version            string   `json:"version"`            // 为空时使用兼容矩阵中的默认版本
+	DataRoot           string   `json:"dataRoot"`           // 数据目录，默认 /var/lib/containerd
+	SandboxImage       string   `json:"sandboxImage"`       // 为空时使用兼容矩阵中的 pause 镜像
+	InsecureRegistries []string `json:"insecureRegistries"` // 允许 HTTP 访问或跳过 TLS 校验的仓库
+}
+
+// ResolvedConfig 根据其他配置项计算得到的值，由 ApplyDefaults 填充，供 playbook 和模板使用
+type ResolvedConfig struct {
+	KubeadmAPIVersion    string            `json:"kubeadmApiVersion"`    // kubeadm 配置 API 版本，如 v1beta3
+	PackageRepoPath      string            `json:"packageRepoPath"`      // kubernetes 软件源中的版本路径
+	PauseImage           string            `json:"pauseImage"`           // 完整的 sandbox 镜像地址
+	Registries           []RegistryMirror  `json:"registries"`           // 合并镜像加速与非安全仓库后的仓库配置
+	CRISocket            string            `json:"criSocket"`            // 容器运行时的 CRI 地址
+	EtcdVersion          string            `json:"etcdVersion"`          // 集群 etcd 版本
+	KeepalivedPriority   map[string]int    `json:"keepalivedPriority"`   // 各 Master 的 VRRP 优先级，首个 Master 最高
+	CertSANs             []string          `json:"certSANs"`             // API Server 证书的额外 SAN，包含 VIP、入口域名与 CertSANs
+	ControlPlaneEndpoint string            `json:"controlPlaneEndpoint"` // kubeadm controlPlaneEndpoint，host:port
+	IPFamilies           []string          `json:"ipFamilies"`           // 集群地址族，IPv4 或 IPv6，第一个为主地址族
+	ClusterDNS           string            `json:"clusterDNS"`           // CoreDNS Service 地址，取主 Service 网段的第 10 个地址
+	NodeIPs              map[string]string `json:"nodeIPs"`              // 各主机的 kubelet node-ip，双栈时以逗号分隔
+	EtcdEndpoints        []string          `json:"etcdEndpoints"`        // 外部 etcd 的客户端地址，按主机名排序
+	KubeVipImage         string            `json:"kubeVipImage"`         // kube-vip 静态 Pod 镜像
+	CNIVersion           string            `json:"cniVersion"`           // 网络插件版本，取兼容矩阵中的版本
+	MetalLBPools         []MetalLBPool     `json:"metalLBPools"`         // MetalLB.Pools，为空时由 LoadBalancerIP 生成
+}
+
+// APIServerConfig API Server 端口与证书配置
+type APIServerConfig struct {
+	BindPort    int      `json:"bindPort"`    // API Server 监听端口，默认 6443
+	LBPort      int      `json:"lbPort"`      // keepalived-nginx 与 keepalived-haproxy 的代理端口，默认 8443
+	EndpointDNS string   `json:"endpointDNS"` // 控制面入口的域名，设置后 controlPlaneEndpoint 使用该域名，需解析到 VIP
+	CertSANs    []string `json:"certSANs"`    // API Server 证书额外的 SAN，可以是域名或 IP
+}
+
+// KeepalivedConfig keepalived VRRP 配置
+type KeepalivedConfig struct {
+	VirtualRouterID int    `json:"virtualRouterId"` // VRID，同一二层网络中的集群不能重复，默认取 VIP 最后一段
+	AuthPass        string `json:"authPass"`        // VRRP 认证密码，最长 8 位，为空时随机生成
+	Unicast         bool   `json:"unicast"`         // 使用单播与其他 Master 通信，适用于禁止组播的网络
+}
+
+// EtcdConfig etcd 拓扑配置
+type EtcdConfig struct {
+	Topology string            `json:"topology"` // stacked（Master 上的静态 Pod）或 external，默认 stacked
+	Hosts    map[string]string `json:"hosts"`    // external 模式下的 etcd 主机，主机名到 IP
+	DataDir  string            `json:"dataDir"`  // external 模式下的数据目录，默认 /var/lib/etcd
+}
+
+// ArtifactsConfig 内置制品服务器配置，启用后 playbook 从 KubeCraft 主机下载软件包和镜像
+type ArtifactsConfig struct {
+	Enabled bool   `json:"enabled"`
+	URL     string `json:"url"` // 制品地址，如 http://172.16.32.10:8080/artifacts
+}
+
+// MirrorsConfig 软件源、镜像仓库和 Helm 仓库配置，为空时使用默认的阿里云地址
+type MirrorsConfig struct {
+	PackageRepos    PackageReposConfig `json:"packageRepos"`
+	ImageRepository string             `json:"imageRepository"` // kubeadm 拉取控制面镜像的仓库
+	Registries      []RegistryMirror   `json:"registries"`      // containerd 镜像加速与私有仓库
+	HelmRepo        string             `json:"helmRepo"`        // Helm Chart 仓库地址
+	GitHub          string             `json:"github"`          // GitHub Release 下载地址或代理
+}
+
+// PackageReposConfig yum 软件源配置，为空的源不做修改
+type PackageReposConfig struct {
+	BaseOS     string `json:"baseOS"`     // CentOS-Base.repo 下载地址
+	EPEL       string `json:"epel"`       // epel.repo 下载地址
+	Kubernetes string `json:"kubernetes"` // kubernetes 仓库地址前缀，baseurl 为前缀加版本路径
+	CRIO       string `json:"crio"`       // cri-o 仓库地址前缀，baseurl 为前缀加版本路径
+}
+
+// RegistryMirror containerd 镜像仓库配置，写入 /etc/containerd/certs.d/<registry>/hosts.toml
+type RegistryMirror struct {
+	Registry   string   `json:"registry"`   // 被加速的仓库，如 docker.io
+	Endpoints  []string `json:"endpoints"`  // 镜像地址，按顺序尝试
+	Username   string   `json:"username"`   // 认证用户名
+	Password   string   `json:"password"`   // 认证密码
+	CA         string   `json:"ca"`         // PEM 格式的 CA 证书内容
+	SkipVerify bool     `json:"skipVerify"` // 跳过 TLS 校验
 }
 
 // GenerateDefaultInventory 生成 Ansible 默认的 inventory 文件并写入 /etc/ansible/hosts
@@ -62,6 +163,22 @@ func (config *Config) GenerateDefaultInventory() error {
 		}
 	}
 
+	// 写入 etcd 组，仅外部 etcd 拓扑使用
+	builder.WriteString("\n# External etcd Hosts\n")
+	builder.WriteString("[etcd]\n")
+	if config.Etcd.Topology == EtcdExternal {
+		for hostname, ip := range config.Etcd.Hosts {
+			if hostname != "" && ip != "" {
+				builder.WriteString(fmt.Sprintf("%s ansible_host=%s\n", hostname, ip))
+			}
+		}
+	}
+
+	// 写入 kubernetes 组，包含所有 Kubernetes 节点
+	builder.WriteString("\n[kubernetes:children]\n")
+	builder.WriteString("masters\n")
+	builder.WriteString("nodes\n")
+
 	// 写入 all 组的变量
 	builder.WriteString("\n# Global Variables\n")
 	builder.WriteString("[all:vars]\n")
@@ -75,12 +192,15 @@ func (config *Config) GenerateDefaultInventory() error {
 		return fmt.Errorf("failed to create /etc/ansible directory: %v", err)
 	}
 
-	// 创建或截断 inventory 文件
-	file, err := os.Create("/etc/ansible/hosts")
+	// 创建或截断 inventory 文件，其中包含 SSH 密码，仅 root 可读写
+	file, err := os.OpenFile("/etc/ansible/hosts", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return fmt.Errorf("failed to create inventory file: %v", err)
 	}
 	defer file.Close()
+	if err := file.Chmod(0600); err != nil {
+		return fmt.Errorf("failed to set inventory file permissions: %v", err)
+	}
 
 	// 写入 inventory 内容到文件
 	_, err = file.WriteString(builder.String())
@@ -124,6 +244,21 @@ func CopyFile(src, dst string) error {
 	return destFile.Sync()
 }
 
+// FileSHA256 计算文件的 sha256
+func FileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // IsCommandAvailable 检查命令是否可用
 func IsCommandAvailable(cmd string) bool {
 	_, err := exec.LookPath(cmd)
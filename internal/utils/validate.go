@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Validate 校验配置，部署前调用，需在 ApplyDefaults 之后执行
+func (config *Config) Validate() error {
+	info, err := LookupVersion(config.KubernetesVersion)
+	if err != nil {
+		return fmt.Errorf("kubernetesVersion: %v", err)
+	}
+
+	if _, ok := CRISockets[config.ContainerRuntime]; !ok {
+		return fmt.Errorf("containerRuntime: unsupported runtime %q, supported: %s, %s",
+			config.ContainerRuntime, RuntimeContainerd, RuntimeCRIO)
+	}
+
+	if config.ContainerRuntime == RuntimeContainerd && !slices.Contains(info.ContainerdVersions, config.Containerd.Version) {
+		return fmt.Errorf("containerd.version: %s is not compatible with Kubernetes %s, supported: %s",
+			config.Containerd.Version, config.KubernetesVersion, strings.Join(info.ContainerdVersions, ", "))
+	}
+
+	if config.Artifacts.Enabled && config.Artifacts.URL == "" {
+		return fmt.Errorf("artifacts.url: required when artifacts are enabled, set it or KUBECRAFT_ADVERTISE_ADDRESS")
+	}
+
+	if err := config.validateNetwork(); err != nil {
+		return err
+	}
+	if err := config.validateControlPlaneLB(); err != nil {
+		return err
+	}
+	if err := config.validateEtcd(); err != nil {
+		return err
+	}
+	if err := config.validateMetalLB(); err != nil {
+		return err
+	}
+	if err := config.validateAddons(); err != nil {
+		return err
+	}
+
+	if config.UsesKeepalived() {
+		if id := config.Keepalived.VirtualRouterID; id < 1 || id > 255 {
+			return fmt.Errorf("keepalived.virtualRouterId: %d is out of range 1-255", id)
+		}
+		if config.Keepalived.AuthPass == "" {
+			return fmt.Errorf("keepalived.authPass: failed to generate a password, set it explicitly")
+		}
+		if len(config.Keepalived.AuthPass) > 8 {
+			return fmt.Errorf("keepalived.authPass: must be at most 8 characters, keepalived truncates longer passwords")
+		}
+	}
+
+	return nil
+}
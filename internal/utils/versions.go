@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultKubernetesVersion 未指定版本时部署的 Kubernetes 版本
+const DefaultKubernetesVersion = "1.28.1"
+
+// VersionInfo Kubernetes 次版本的兼容信息
+type VersionInfo struct {
+	KubeadmAPIVersion  string            // kubeadm 配置文件的 API 版本
+	ContainerdVersions []string          // 兼容的 containerd 版本，第一个为默认版本
+	CNIVersions        map[string]string // 各 CNI 插件的兼容版本
+	PackageRepoPath    string            // kubernetes 软件源下该版本的路径
+	PauseImage         string            // sandbox 镜像名称与标签
+	EtcdVersion        string            // kubeadm 默认部署的 etcd 版本，用于下载 etcdutl
+}
+
+// SupportedVersions 支持的 Kubernetes 次版本兼容矩阵
+var SupportedVersions = map[string]VersionInfo{
+	"1.28": {
+		KubeadmAPIVersion:  "v1beta3",
+		ContainerdVersions: []string{"1.7.13", "1.6.28", "1.6.4"},
+		CNIVersions:        map[string]string{"cilium": "1.14.5", "calico": "v3.26.4", "flannel": "v0.24.2"},
+		PackageRepoPath:    "v1.28/rpm/",
+		PauseImage:         "pause:3.9",
+		EtcdVersion:        "3.5.9",
+	},
+	"1.29": {
+		KubeadmAPIVersion:  "v1beta3",
+		ContainerdVersions: []string{"1.7.13", "1.6.28"},
+		CNIVersions:        map[string]string{"cilium": "1.15.1", "calico": "v3.27.2", "flannel": "v0.24.2"},
+		PackageRepoPath:    "v1.29/rpm/",
+		PauseImage:         "pause:3.9",
+		EtcdVersion:        "3.5.10",
+	},
+	"1.30": {
+		KubeadmAPIVersion:  "v1beta3",
+		ContainerdVersions: []string{"1.7.16", "1.6.31"},
+		CNIVersions:        map[string]string{"cilium": "1.15.5", "calico": "v3.28.0", "flannel": "v0.25.1"},
+		PackageRepoPath:    "v1.30/rpm/",
+		PauseImage:         "pause:3.9",
+		EtcdVersion:        "3.5.12",
+	},
+	"1.31": {
+		KubeadmAPIVersion:  "v1beta4",
+		ContainerdVersions: []string{"1.7.22"},
+		CNIVersions:        map[string]string{"cilium": "1.16.1", "calico": "v3.28.1", "flannel": "v0.25.6"},
+		PackageRepoPath:    "v1.31/rpm/",
+		PauseImage:         "pause:3.10",
+		EtcdVersion:        "3.5.15",
+	},
+}
+
+// versionPattern Kubernetes 版本格式，如 1.28.1
+var versionPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)$`)
+
+// KubernetesMinor 返回版本号对应的次版本，如 1.28.1 返回 1.28
+func KubernetesMinor(version string) (string, error) {
+	match := versionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return "", fmt.Errorf("invalid Kubernetes version %q, expected format like 1.28.1", version)
+	}
+	return match[1] + "." + match[2], nil
+}
+
+// LookupVersion 查找 Kubernetes 版本的兼容信息
+func LookupVersion(version string) (VersionInfo, error) {
+	minor, err := KubernetesMinor(version)
+	if err != nil {
+		return VersionInfo{}, err
+	}
+
+	info, ok := SupportedVersions[minor]
+	if !ok {
+		return VersionInfo{}, fmt.Errorf("unsupported Kubernetes version %s, supported minors: %s",
+			version, strings.Join(supportedMinors(), ", "))
+	}
+	return info, nil
+}
+
+// supportedMinors 返回排序后的受支持次版本列表
+func supportedMinors() []string {
+	minors := make([]string, 0, len(SupportedVersions))
+	for minor := range SupportedVersions {
+		minors = append(minors, minor)
+	}
+	sort.Strings(minors)
+	return minors
+}
+
+// CheckUpgrade 检查是否可以从 from 升级到 to：只允许同一次版本内升级补丁版本，或升级到下一个次版本
+func CheckUpgrade(from, to string) error {
+	current, err := parseVersion(from)
+	if err != nil {
+		return err
+	}
+	target, err := parseVersion(to)
+	if err != nil {
+		return err
+	}
+	if _, err := LookupVersion(to); err != nil {
+		return err
+	}
+
+	switch {
+	case target[0] != current[0]:
+		return fmt.Errorf("cannot upgrade across major versions from %s to %s", from, to)
+	case target[1] == current[1] && target[2] > current[2]:
+		return nil
+	case target[1] == current[1]+1:
+		return nil
+	case target[1] < current[1] || (target[1] == current[1] && target[2] <= current[2]):
+		return fmt.Errorf("target version %s is not newer than %s", to, from)
+	default:
+		return fmt.Errorf("cannot skip minor versions when upgrading from %s to %s", from, to)
+	}
+}
+
+// UpgradeTo 将配置切换到目标 Kubernetes 版本并重新填充默认值。
+// containerd 版本不在目标版本兼容列表中时改用目标版本的默认 containerd，沿用默认 pause 镜像的 sandbox 镜像随目标版本更新。
+func (config *Config) UpgradeTo(version string) error {
+	info, err := LookupVersion(version)
+	if err != nil {
+		return err
+	}
+
+	// 先按当前版本计算默认值，以判断 sandbox 镜像是否为默认 pause 镜像
+	config.ApplyDefaults()
+	if config.Containerd.SandboxImage == config.Resolved.PauseImage {
+		config.Containerd.SandboxImage = ""
+	}
+	if !slices.Contains(info.ContainerdVersions, config.Containerd.Version) {
+		config.Containerd.Version = info.ContainerdVersions[0]
+	}
+	config.KubernetesVersion = version
+	config.ApplyDefaults()
+	return nil
+}
+
+// parseVersion 解析 Kubernetes 版本为主、次、补丁版本号
+func parseVersion(version string) ([3]int, error) {
+	var parts [3]int
+	match := versionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return parts, fmt.Errorf("invalid Kubernetes version %q, expected format like 1.28.1", version)
+	}
+	for i := range parts {
+		parts[i], _ = strconv.Atoi(match[i+1])
+	}
+	return parts, nil
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+// testConfig 返回单 Master 的最小可校验配置
+func testConfig(version string) Config {
+	return Config{
+		FirstMasterHostname: "master1",
+		Masters:             map[string]string{"master1": "192.168.1.10"},
+		NetworkAdapter:      "eth0",
+		ControlPlaneLB:      LBNone,
+		ServiceNetwork:      "10.96.0.0/12",
+		PodNetwork:          "10.244.0.0/16",
+		KubernetesVersion:   version,
+	}
+}
+
+func TestUpgradeMatrix(t *testing.T) {
+	tests := []struct {
+		name             string
+		from, to         string
+		containerd       string // 升级前的 containerd 版本，为空时使用 from 的默认版本
+		sandboxImage     string // 升级前的 sandbox 镜像，为空时使用 from 的默认 pause 镜像
+		wantErr          string // CheckUpgrade 的错误
+		wantContainerd   string
+		wantSandboxImage string
+	}{
+		{name: "patch", from: "1.28.1", to: "1.28.9", wantContainerd: "1.7.13", wantSandboxImage: "pause:3.9"},
+		{name: "1.28 to 1.29", from: "1.28.1", to: "1.29.2", wantContainerd: "1.7.13", wantSandboxImage: "pause:3.9"},
+		{name: "1.28 to 1.29 keeps compatible containerd", from: "1.28.1", to: "1.29.2", containerd: "1.6.28",
+			wantContainerd: "1.6.28", wantSandboxImage: "pause:3.9"},
+		{name: "1.28 to 1.29 replaces incompatible containerd", from: "1.28.1", to: "1.29.2", containerd: "1.6.4",
+			wantContainerd: "1.7.13", wantSandboxImage: "pause:3.9"},
+		{name: "1.29 to 1.30", from: "1.29.2", to: "1.30.1", wantContainerd: "1.7.16", wantSandboxImage: "pause:3.9"},
+		{name: "1.30 to 1.31", from: "1.30.1", to: "1.31.0", wantContainerd: "1.7.22", wantSandboxImage: "pause:3.10"},
+		{name: "custom sandbox image is kept", from: "1.30.1", to: "1.31.0", sandboxImage: "registry.local/pause:3.9",
+			wantContainerd: "1.7.22", wantSandboxImage: "registry.local/pause:3.9"},
+		{name: "skip minor", from: "1.28.1", to: "1.30.1", wantErr: "cannot skip minor versions"},
+		{name: "downgrade", from: "1.29.2", to: "1.28.1", wantErr: "is not newer than"},
+		{name: "same version", from: "1.29.2", to: "1.29.2", wantErr: "is not newer than"},
+		{name: "unsupported target", from: "1.31.0", to: "1.32.0", wantErr: "unsupported Kubernetes version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig(tt.from)
+			config.Containerd.Version = tt.containerd
+			config.Containerd.SandboxImage = tt.sandboxImage
+			config.ApplyDefaults()
+			if err := config.Validate(); err != nil {
+				t.Fatalf("config for %s is invalid: %v", tt.from, err)
+			}
+
+			err := CheckUpgrade(config.KubernetesVersion, tt.to)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("CheckUpgrade(%s, %s) error = %v, want %q", tt.from, tt.to, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckUpgrade(%s, %s) error = %v", tt.from, tt.to, err)
+			}
+
+			if err := config.UpgradeTo(tt.to); err != nil {
+				t.Fatalf("UpgradeTo(%s) error = %v", tt.to, err)
+			}
+			if err := config.Validate(); err != nil {
+				t.Fatalf("config upgraded to %s is invalid: %v", tt.to, err)
+			}
+			if config.Containerd.Version != tt.wantContainerd {
+				t.Errorf("containerd version = %s, want %s", config.Containerd.Version, tt.wantContainerd)
+			}
+			if !strings.HasSuffix(config.Containerd.SandboxImage, tt.wantSandboxImage) {
+				t.Errorf("sandbox image = %s, want suffix %s", config.Containerd.SandboxImage, tt.wantSandboxImage)
+			}
+		})
+	}
+}
@@ -0,0 +1,499 @@
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"KubeCraft/internal/utils"
+)
+
+// 验证时创建的测试资源
+const (
+	Namespace   = "kubecraft-verify"
+	testApp     = "kubecraft-verify"
+	testHost    = "verify.kubecraft.local"
+	testPort    = 8080
+	waitTimeout = "3m"
+)
+
+// DefaultTestImage 测试工作负载使用的镜像，通过 containerd 镜像加速拉取
+const DefaultTestImage = "docker.io/library/busybox:1.36"
+
+// ProgressReporter 进度报告接口
+type ProgressReporter interface {
+	ReportProgress(message string)
+}
+
+// Check 单项检查结果
+type Check struct {
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Skipped  bool          `json:"skipped"`
+	Message  string        `json:"message"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Report 验证报告
+type Report struct {
+	Passed     bool      `json:"passed"`
+	Checks     []Check   `json:"checks"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// Failed 返回未通过的检查名称
+func (r *Report) Failed() []string {
+	var failed []string
+	for _, check := range r.Checks {
+		if !check.Passed && !check.Skipped {
+			failed = append(failed, check.Name)
+		}
+	}
+	return failed
+}
+
+// Save 将报告保存为 JSON 文件
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// errSkipped 检查不适用于当前配置
+type errSkipped string
+
+func (e errSkipped) Error() string { return string(e) }
+
+// step 验证步骤
+type step struct {
+	name string
+	run  func(v *verifier) (string, error)
+}
+
+// steps 按顺序执行的验证步骤，后续步骤依赖测试工作负载
+var steps = []step{
+	{"节点就绪", (*verifier).checkNodes},
+	{"控制面组件", (*verifier).checkControlPlane},
+	{"CoreDNS", (*verifier).checkCoreDNS},
+	{"控制面入口访问 API Server", (*verifier).checkEndpoint},
+	{"部署测试工作负载", (*verifier).deployWorkload},
+	{"Pod 间网络", (*verifier).checkPodToPod},
+	{"Pod 访问 Service", (*verifier).checkPodToService},
+	{"NFS PVC 绑定", (*verifier).checkNFS},
+	{"MetalLB 分配地址", (*verifier).checkMetalLB},
+	{"Ingress 路由", (*verifier).checkIngress},
+}
+
+// Steps 返回验证步骤数量，用于计算进度
+func Steps() int {
+	return len(steps)
+}
+
+// Run 执行所有验证步骤并清理测试资源，单项失败不影响其他检查
+func Run(kubeconfig string, config utils.Config, reporter ProgressReporter) *Report {
+	v := &verifier{kubeconfig: kubeconfig, config: config}
+	report := &Report{Passed: true, StartedAt: time.Now()}
+	defer v.cleanup()
+
+	for _, s := range steps {
+		reporter.ReportProgress(fmt.Sprintf("验证%s...", s.name))
+
+		start := time.Now()
+		message, err := s.run(v)
+		check := Check{Name: s.name, Passed: err == nil, Message: message, Duration: time.Since(start)}
+		if skipped, ok := err.(errSkipped); ok {
+			check.Skipped = true
+			check.Message = string(skipped)
+		} else if err != nil {
+			check.Message = err.Error()
+			report.Passed = false
+		}
+		log.Printf("Verify %s: passed=%t skipped=%t %s", s.name, check.Passed, check.Skipped, check.Message)
+		report.Checks = append(report.Checks, check)
+	}
+
+	report.FinishedAt = time.Now()
+	return report
+}
+
+// verifier 保存验证过程中共享的状态
+type verifier struct {
+	kubeconfig string
+	config     utils.Config
+	pods       []pod // 测试工作负载的 Pod
+}
+
+// pod Pod 名称、IP 与所在节点
+type pod struct {
+	name string
+	ip   string
+	node string
+}
+
+// checkNodes 检查所有节点已注册且 Ready
+func (v *verifier) checkNodes() (string, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Conditions []condition `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := v.getJSON(&list, "get", "nodes"); err != nil {
+		return "", err
+	}
+
+	ready := make(map[string]bool)
+	for _, node := range list.Items {
+		ready[node.Metadata.Name] = conditionTrue(node.Status.Conditions, "Ready")
+	}
+
+	var problems []string
+	for _, hosts := range []map[string]string{v.config.Masters, v.config.Nodes} {
+		for host := range hosts {
+			isReady, found := ready[host]
+			switch {
+			case !found:
+				problems = append(problems, host+" not registered")
+			case !isReady:
+				problems = append(problems, host+" not ready")
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return "", fmt.Errorf("%s", strings.Join(problems, ", "))
+	}
+	return fmt.Sprintf("%d nodes ready", len(list.Items)), nil
+}
+
+// checkControlPlane 检查控制面静态 Pod 均已就绪
+func (v *verifier) checkControlPlane() (string, error) {
+	var list podList
+	if err := v.getJSON(&list, "-n", "kube-system", "get", "pods", "-l", "tier=control-plane"); err != nil {
+		return "", err
+	}
+
+	var problems []string
+	for _, item := range list.Items {
+		if !conditionTrue(item.Status.Conditions, "Ready") {
+			problems = append(problems, item.Metadata.Name)
+		}
+	}
+	// 每个 Master 运行 apiserver、controller-manager、scheduler，stacked 拓扑还有 etcd
+	perMaster := 4
+	if v.config.IsExternalEtcd() {
+		perMaster = 3
+	}
+	if want := perMaster * len(v.config.Masters); len(list.Items) < want {
+		return "", fmt.Errorf("found %d control-plane pods, expected %d", len(list.Items), want)
+	}
+	if len(problems) > 0 {
+		return "", fmt.Errorf("not ready: %s", strings.Join(problems, ", "))
+	}
+	return fmt.Sprintf("%d control-plane pods ready", len(list.Items)), nil
+}
+
+// checkCoreDNS 检查 CoreDNS 部署完成
+func (v *verifier) checkCoreDNS() (string, error) {
+	_, err := v.kubectl("-n", "kube-system", "rollout", "status", "deployment/coredns", "--timeout="+waitTimeout)
+	return "coredns rolled out", err
+}
+
+// checkEndpoint 通过控制面负载均衡入口访问 API Server
+func (v *verifier) checkEndpoint() (string, error) {
+	server := v.config.ControlPlaneURL()
+	if _, err := v.kubectl("--server", server, "get", "--raw", "/readyz"); err != nil {
+		return "", err
+	}
+	return server + " ready", nil
+}
+
+// deployWorkload 部署跨节点分布的 HTTP 测试工作负载与 Service
+func (v *verifier) deployWorkload() (string, error) {
+	labels := map[string]any{"app": testApp}
+	manifests := []map[string]any{
+		{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata":   map[string]any{"name": Namespace},
+		},
+		{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]any{"name": testApp, "namespace": Namespace},
+			"spec": map[string]any{
+				"replicas": 2,
+				"selector": map[string]any{"matchLabels": labels},
+				"template": map[string]any{
+					"metadata": map[string]any{"labels": labels},
+					"spec": map[string]any{
+						"affinity": map[string]any{
+							"podAntiAffinity": map[string]any{
+								"preferredDuringSchedulingIgnoredDuringExecution": []any{map[string]any{
+									"weight": 100,
+									"podAffinityTerm": map[string]any{
+										"labelSelector": map[string]any{"matchLabels": labels},
+										"topologyKey":   "kubernetes.io/hostname",
+									},
+								}},
+							},
+						},
+						"containers": []any{map[string]any{
+							"name":    "http",
+							"image":   DefaultTestImage,
+							"command": []string{"sh", "-c", fmt.Sprintf("mkdir -p /www && hostname > /www/index.html && httpd -f -p %d -h /www", testPort)},
+							"ports":   []any{map[string]any{"containerPort": testPort}},
+						}},
+					},
+				},
+			},
+		},
+		{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]any{"name": testApp, "namespace": Namespace},
+			"spec": map[string]any{
+				"selector": labels,
+				"ports":    []any{map[string]any{"port": 80, "targetPort": testPort}},
+			},
+		},
+	}
+	for _, manifest := range manifests {
+		if err := utils.KubectlApply(v.kubeconfig, manifest); err != nil {
+			return "", err
+		}
+	}
+
+	_, err := v.kubectl("-n", Namespace, "rollout", "status", "deployment/"+testApp, "--timeout="+waitTimeout)
+	if err != nil {
+		return "", err
+	}
+
+	var list podList
+	if err := v.getJSON(&list, "-n", Namespace, "get", "pods", "-l", "app="+testApp); err != nil {
+		return "", err
+	}
+	for _, item := range list.Items {
+		if item.Metadata.DeletionTimestamp == "" && item.Status.PodIP != "" {
+			v.pods = append(v.pods, pod{name: item.Metadata.Name, ip: item.Status.PodIP, node: item.Spec.NodeName})
+		}
+	}
+	if len(v.pods) < 2 {
+		return "", fmt.Errorf("expected 2 running test pods, found %d", len(v.pods))
+	}
+	return fmt.Sprintf("test pods on %s and %s", v.pods[0].node, v.pods[1].node), nil
+}
+
+// checkPodToPod 从一个测试 Pod 访问另一个测试 Pod 的 IP
+func (v *verifier) checkPodToPod() (string, error) {
+	if len(v.pods) < 2 {
+		return "", errSkipped("test workload is not running")
+	}
+
+	from, to := v.pods[0], v.pods[1]
+	if err := v.fetch(from.name, fmt.Sprintf("http://%s:%d/", to.ip, testPort), ""); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s (%s) -> %s (%s)", from.name, from.node, to.name, to.node), nil
+}
+
+// checkPodToService 通过 Service 域名访问测试工作负载，同时验证集群 DNS
+func (v *verifier) checkPodToService() (string, error) {
+	if len(v.pods) == 0 {
+		return "", errSkipped("test workload is not running")
+	}
+
+	url := fmt.Sprintf("http://%s.%s.svc.cluster.local/", testApp, Namespace)
+	if err := v.fetch(v.pods[0].name, url, ""); err != nil {
+		return "", err
+	}
+	return url, nil
+}
+
+// checkNFS 创建使用 nfs-csi StorageClass 的 PVC 并等待绑定
+func (v *verifier) checkNFS() (string, error) {
+	if !v.config.AddonEnabled(utils.AddonNfsCsi) {
+		return "", errSkipped("NFS CSI is disabled")
+	}
+
+	err := utils.KubectlApply(v.kubeconfig, map[string]any{
+		"apiVersion": "v1",
+		"kind":       "PersistentVolumeClaim",
+		"metadata":   map[string]any{"name": testApp, "namespace": Namespace},
+		"spec": map[string]any{
+			"accessModes":      []string{"ReadWriteMany"},
+			"storageClassName": "nfs-csi",
+			"resources":        map[string]any{"requests": map[string]any{"storage": "1Mi"}},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	_, err = v.kubectl("-n", Namespace, "wait", "--for=jsonpath={.status.phase}=Bound", "pvc/"+testApp, "--timeout="+waitTimeout)
+	if err != nil {
+		return "", err
+	}
+	return "pvc bound", nil
+}
+
+// checkMetalLB 创建 LoadBalancer Service 并等待 MetalLB 分配地址
+func (v *verifier) checkMetalLB() (string, error) {
+	if !v.config.AddonEnabled(utils.AddonMetalLB) {
+		return "", errSkipped("MetalLB is disabled")
+	}
+	autoAssign := slices.ContainsFunc(v.config.Resolved.MetalLBPools, func(pool utils.MetalLBPool) bool {
+		return pool.AutoAssign == nil || *pool.AutoAssign
+	})
+	if !autoAssign {
+		return "", errSkipped("no MetalLB address pool with autoAssign is configured")
+	}
+
+	name := testApp + "-lb"
+	err := utils.KubectlApply(v.kubeconfig, map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata":   map[string]any{"name": name, "namespace": Namespace},
+		"spec": map[string]any{
+			"type":     "LoadBalancer",
+			"selector": map[string]any{"app": testApp},
+			"ports":    []any{map[string]any{"port": 80, "targetPort": testPort}},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for i := 0; i < 60; i++ {
+		ip, err := v.kubectl("-n", Namespace, "get", "svc", name, "-o", "jsonpath={.status.loadBalancer.ingress[0].ip}")
+		if err != nil {
+			return "", err
+		}
+		if ip = strings.TrimSpace(ip); ip != "" {
+			return "assigned " + ip, nil
+		}
+		time.Sleep(2 * time.Second)
+	}
+	return "", fmt.Errorf("no external IP assigned to service %s", name)
+}
+
+// checkIngress 创建测试 Ingress 并通过 ingress-nginx 控制器访问
+func (v *verifier) checkIngress() (string, error) {
+	if !v.config.AddonEnabled(utils.AddonIngressNginx) {
+		return "", errSkipped("Ingress Nginx is disabled")
+	}
+	if len(v.pods) == 0 {
+		return "", errSkipped("test workload is not running")
+	}
+
+	err := utils.KubectlApply(v.kubeconfig, map[string]any{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "Ingress",
+		"metadata":   map[string]any{"name": testApp, "namespace": Namespace},
+		"spec": map[string]any{
+			"ingressClassName": "nginx",
+			"rules": []any{map[string]any{
+				"host": testHost,
+				"http": map[string]any{"paths": []any{map[string]any{
+					"path":     "/",
+					"pathType": "Prefix",
+					"backend": map[string]any{"service": map[string]any{
+						"name": testApp,
+						"port": map[string]any{"number": 80},
+					}},
+				}}},
+			}},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// 控制器加载新规则需要时间
+	url := "http://ingress-nginx-controller.ingress-nginx.svc.cluster.local/"
+	for i := 0; i < 30; i++ {
+		if err = v.fetch(v.pods[0].name, url, testHost); err == nil {
+			return "routed " + testHost, nil
+		}
+		time.Sleep(2 * time.Second)
+	}
+	return "", err
+}
+
+// cleanup 删除测试命名空间
+func (v *verifier) cleanup() {
+	_, err := v.kubectl("delete", "namespace", Namespace, "--ignore-not-found", "--wait=false")
+	if err != nil {
+		log.Printf("Failed to delete namespace %s: %v", Namespace, err)
+	}
+}
+
+// fetch 在测试 Pod 中请求 url
+func (v *verifier) fetch(podName, url, host string) error {
+	args := []string{"-n", Namespace, "exec", podName, "--", "wget", "-q", "-O-", "-T", "5"}
+	if host != "" {
+		args = append(args, "--header", "Host: "+host)
+	}
+	_, err := v.kubectl(append(args, url)...)
+	return err
+}
+
+// kubectl 使用集群 kubeconfig 执行 kubectl
+func (v *verifier) kubectl(args ...string) (string, error) {
+	return utils.Kubectl(v.kubeconfig, args...)
+}
+
+// getJSON 执行 kubectl get 并解析 JSON 输出
+func (v *verifier) getJSON(out any, args ...string) error {
+	output, err := v.kubectl(append(args, "-o", "json")...)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(output), out); err != nil {
+		return fmt.Errorf("failed to decode kubectl output: %v", err)
+	}
+	return nil
+}
+
+// condition Kubernetes 对象的状态条件
+type condition struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+}
+
+// conditionTrue 判断指定条件是否为 True
+func conditionTrue(conditions []condition, conditionType string) bool {
+	for _, c := range conditions {
+		if c.Type == conditionType {
+			return c.Status == "True"
+		}
+	}
+	return false
+}
+
+// podList kubectl get pods -o json 的输出
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name              string `json:"name"`
+			DeletionTimestamp string `json:"deletionTimestamp"`
+		} `json:"metadata"`
+		Spec struct {
+			NodeName string `json:"nodeName"`
+		} `json:"spec"`
+		Status struct {
+			PodIP      string      `json:"podIP"`
+			Conditions []condition `json:"conditions"`
+		} `json:"status"`
+	} `json:"items"`
+}